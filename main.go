@@ -1,16 +1,13 @@
 package main
 
 import (
-	"go_final_project/pkg/db"
-	"go_final_project/pkg/server"
+	"go_final_project/pkg/cli"
 	"log"
+	"os"
 )
 
 func main() {
-	if err := db.Init("scheduler.db"); err != nil {
-		log.Fatal(err)
-	}
-	if err := server.Run(); err != nil {
+	if err := cli.Run(os.Args[1:], os.Stdout); err != nil {
 		log.Fatal(err)
 	}
 }
@@ -4,8 +4,28 @@ import (
 	"net/http"
 )
 
-func Init() {
+// service handles tasks for the HTTP handlers
+var service *TaskService
+
+// Init registers the API handlers working with the given service
+func Init(svc *TaskService) {
+	service = svc
+
 	http.HandleFunc("/api/nextdate", nextDateHandler)
-	http.HandleFunc("/api/task", taskHandler)
-	http.HandleFunc("/api/tasks", tasksHandler)
+	http.HandleFunc("/api/nextdate/preview", previewHandler)
+	http.HandleFunc("/api/signin", signinHandler)
+	http.HandleFunc("/api/task", auth(taskHandler))
+	http.HandleFunc("/api/task/done", auth(doneHandler))
+	http.HandleFunc("/api/task/history", auth(historyHandler))
+	http.HandleFunc("/api/task/restore", auth(restoreHandler))
+	http.HandleFunc("/api/trash", auth(trashHandler))
+	http.HandleFunc("/api/tasks", auth(tasksHandler))
+	http.HandleFunc("/api/tasks/overdue", auth(overdueHandler))
+	http.HandleFunc("/api/tasks/today", auth(todayHandler))
+	http.HandleFunc("/api/tags", auth(tagsHandler))
+	http.HandleFunc("/api/calendar.ics", calendarAuth(calendarHandler))
+	http.HandleFunc("/api/calendar/token", auth(calendarTokenHandler))
+	http.HandleFunc("/api/import/ics", auth(importICSHandler))
+	http.HandleFunc("/api/export", auth(exportHandler))
+	http.HandleFunc("/api/import", auth(importHandler))
 }
@@ -0,0 +1,172 @@
+package api
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// tokenTTL matches the lifetime of the token cookie set by the web UI
+const tokenTTL = 8 * time.Hour
+
+// Token scopes: session tokens from /api/signin open the whole API for tokenTTL,
+// feed tokens only open the calendar feed but don't expire, so a calendar app
+// subscribed with one keeps working until the password changes
+const (
+	sessionScope = "session"
+	feedScope    = "feed"
+)
+
+// SigninRequest is the body of POST /api/signin
+type SigninRequest struct {
+	Password string `json:"password"`
+}
+
+// passwordHash returns the hash stored in tokens so a password change invalidates them
+func passwordHash(password string) string {
+	sum := sha256.Sum256([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
+
+// newToken issues a session token signed with the password and bound to its hash
+func newToken(password string) (string, error) {
+	claims := jwt.MapClaims{
+		"hash":  passwordHash(password),
+		"scope": sessionScope,
+		"exp":   time.Now().Add(tokenTTL).Unix(),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(password))
+}
+
+// newFeedToken issues a calendar feed token; it has no expiry and is revoked
+// by changing the password
+func newFeedToken(password string) (string, error) {
+	claims := jwt.MapClaims{
+		"hash":  passwordHash(password),
+		"scope": feedScope,
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(password))
+}
+
+// validToken reports whether the token of the given scope was issued for the
+// password and hasn't expired
+func validToken(tokenStr, password, scope string) bool {
+	token, err := jwt.Parse(tokenStr, func(*jwt.Token) (any, error) {
+		return []byte(password), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !token.Valid {
+		return false
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return false
+	}
+	hash, _ := claims["hash"].(string)
+	tokenScope, _ := claims["scope"].(string)
+	return hash == passwordHash(password) && tokenScope == scope
+}
+
+// signinHandler handles POST /api/signin and returns a token for the correct password
+func signinHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req SigninRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, "invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	password := os.Getenv("TODO_PASSWORD")
+	if password == "" {
+		writeError(w, "authentication is disabled", http.StatusBadRequest)
+		return
+	}
+	// Comparing fixed-size hashes in constant time doesn't leak the password length or prefix
+	if subtle.ConstantTimeCompare([]byte(passwordHash(req.Password)), []byte(passwordHash(password))) != 1 {
+		writeError(w, "invalid password", http.StatusUnauthorized)
+		return
+	}
+
+	token, err := newToken(password)
+	if err != nil {
+		writeError(w, "failed to create token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"token": token})
+}
+
+// calendarTokenHandler handles GET /api/calendar/token and returns a feed token
+// for subscribing to /api/calendar.ics from calendar apps
+func calendarTokenHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	password := os.Getenv("TODO_PASSWORD")
+	if password == "" {
+		writeError(w, "authentication is disabled", http.StatusBadRequest)
+		return
+	}
+
+	token, err := newFeedToken(password)
+	if err != nil {
+		writeError(w, "failed to create token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"token": token})
+}
+
+// auth requires a valid session token cookie when TODO_PASSWORD is set
+func auth(next http.HandlerFunc) http.HandlerFunc {
+	return requireToken(func(r *http.Request, password string) bool {
+		return validToken(cookieToken(r), password, sessionScope)
+	}, next)
+}
+
+// calendarAuth is auth for the calendar feed: calendar apps can't send cookies,
+// so besides the session cookie it accepts a feed token in the token query
+// parameter. Session tokens aren't accepted there, to keep them out of access
+// logs and browser history
+func calendarAuth(next http.HandlerFunc) http.HandlerFunc {
+	return requireToken(func(r *http.Request, password string) bool {
+		return validToken(cookieToken(r), password, sessionScope) ||
+			validToken(r.URL.Query().Get("token"), password, feedScope)
+	}, next)
+}
+
+// cookieToken returns the value of the token cookie, if any
+func cookieToken(r *http.Request) string {
+	if cookie, err := r.Cookie("token"); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+// requireToken calls next only if valid accepts the token of the request for
+// TODO_PASSWORD, or if no password is set
+func requireToken(valid func(r *http.Request, password string) bool, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		password := os.Getenv("TODO_PASSWORD")
+		if password != "" && !valid(r, password) {
+			writeError(w, "authentication required", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// signin posts the password to signinHandler and returns the response
+func signin(t *testing.T, password string) *httptest.ResponseRecorder {
+	body, err := json.Marshal(SigninRequest{Password: password})
+	require.NoError(t, err)
+	rec := httptest.NewRecorder()
+	signinHandler(rec, httptest.NewRequest(http.MethodPost, "/api/signin", bytes.NewReader(body)))
+	return rec
+}
+
+// wrappedStatus returns the status of a request to target through a handler
+// wrapped with wrap, sending the token in a cookie if it isn't empty
+func wrappedStatus(wrap func(http.HandlerFunc) http.HandlerFunc, target, token string) int {
+	handler := wrap(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	if token != "" {
+		req.AddCookie(&http.Cookie{Name: "token", Value: token})
+	}
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec.Code
+}
+
+// authStatus returns the status of a request to /api/tasks with the token cookie
+func authStatus(token string) int {
+	return wrappedStatus(auth, "/api/tasks", token)
+}
+
+func TestSignin(t *testing.T) {
+	t.Setenv("TODO_PASSWORD", "secret")
+
+	rec := signin(t, "wrong")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	var m map[string]string
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &m))
+	assert.NotEmpty(t, m["error"])
+	assert.Empty(t, m["token"])
+
+	rec = signin(t, "secret")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &m))
+	assert.True(t, validToken(m["token"], "secret", sessionScope))
+
+	t.Setenv("TODO_PASSWORD", "")
+	assert.Equal(t, http.StatusBadRequest, signin(t, "secret").Code)
+}
+
+func TestAuth(t *testing.T) {
+	t.Setenv("TODO_PASSWORD", "secret")
+	token, err := newToken("secret")
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, authStatus(token))
+	assert.Equal(t, http.StatusUnauthorized, authStatus(""))
+	assert.Equal(t, http.StatusUnauthorized, authStatus("garbage"))
+
+	// An expired token is rejected even though it was signed with the right password
+	expired, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"hash":  passwordHash("secret"),
+		"scope": sessionScope,
+		"exp":   time.Now().Add(-time.Minute).Unix(),
+	}).SignedString([]byte("secret"))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, authStatus(expired))
+
+	// Changing the password invalidates the tokens issued for the old one
+	t.Setenv("TODO_PASSWORD", "another")
+	assert.Equal(t, http.StatusUnauthorized, authStatus(token))
+
+	// Without a password the API is open
+	t.Setenv("TODO_PASSWORD", "")
+	assert.Equal(t, http.StatusOK, authStatus(""))
+}
+
+func TestCalendarAuth(t *testing.T) {
+	t.Setenv("TODO_PASSWORD", "secret")
+	token, err := newToken("secret")
+	require.NoError(t, err)
+	feed, err := newFeedToken("secret")
+	require.NoError(t, err)
+
+	// Only the calendar feed takes a token from the query string, and only a feed token
+	assert.Equal(t, http.StatusUnauthorized, wrappedStatus(auth, "/api/tasks?token="+feed, ""))
+	assert.Equal(t, http.StatusOK, wrappedStatus(calendarAuth, "/api/calendar.ics?token="+feed, ""))
+	assert.Equal(t, http.StatusOK, wrappedStatus(calendarAuth, "/api/calendar.ics", token))
+	assert.Equal(t, http.StatusUnauthorized, wrappedStatus(calendarAuth, "/api/calendar.ics?token="+token, ""))
+	assert.Equal(t, http.StatusUnauthorized, wrappedStatus(calendarAuth, "/api/calendar.ics?token=garbage", ""))
+	assert.Equal(t, http.StatusUnauthorized, wrappedStatus(calendarAuth, "/api/calendar.ics", ""))
+
+	// A feed token doesn't open the rest of the API, even in the cookie
+	assert.Equal(t, http.StatusUnauthorized, authStatus(feed))
+	assert.Equal(t, http.StatusUnauthorized, wrappedStatus(calendarAuth, "/api/calendar.ics", feed))
+
+	// The feed token outlives session tokens but not a password change
+	t.Setenv("TODO_PASSWORD", "another")
+	assert.Equal(t, http.StatusUnauthorized, wrappedStatus(calendarAuth, "/api/calendar.ics?token="+feed, ""))
+}
+
+func TestCalendarToken(t *testing.T) {
+	t.Setenv("TODO_PASSWORD", "secret")
+	rec := httptest.NewRecorder()
+	calendarTokenHandler(rec, httptest.NewRequest(http.MethodGet, "/api/calendar/token", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var m map[string]string
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &m))
+	assert.True(t, validToken(m["token"], "secret", feedScope))
+	assert.False(t, validToken(m["token"], "secret", sessionScope))
+
+	t.Setenv("TODO_PASSWORD", "")
+	rec = httptest.NewRecorder()
+	calendarTokenHandler(rec, httptest.NewRequest(http.MethodGet, "/api/calendar/token", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// icsTimestamp is the UTC date-time format used by iCalendar
+const icsTimestamp = "20060102T150405Z"
+
+// icsDays maps time.Weekday to iCalendar BYDAY codes
+var icsDays = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// icsPriority maps task priority to iCalendar PRIORITY, where 1 is the highest
+var icsPriority = map[int]int{1: 9, 2: 5, 3: 1}
+
+// repeatToRRule translates a repeat rule into an iCalendar RRULE value; its end
+// condition depends on the task and is added by rruleEnd
+func repeatToRRule(rule RepeatRule) string {
+	switch rule.Kind {
+	case RepeatYearly:
+		return "FREQ=YEARLY"
+	case RepeatDaily:
+		return fmt.Sprintf("FREQ=DAILY;INTERVAL=%d", rule.Interval)
+	case RepeatWeekly:
+		var byDay []string
+		// Keep the Monday-first order of the repeat rule
+		for day := 1; day <= 7; day++ {
+			if slices.Contains(rule.Weekdays, day) {
+				byDay = append(byDay, icsDays[day%7])
+			}
+		}
+		return "FREQ=WEEKLY;BYDAY=" + strings.Join(byDay, ",")
+	default:
+		rrule := "FREQ=MONTHLY;BYMONTHDAY=" + joinInts(slices.Sorted(slices.Values(rule.MonthDays)))
+		if len(rule.Months) > 0 {
+			rrule += ";BYMONTH=" + joinInts(slices.Sorted(slices.Values(rule.Months)))
+		}
+		return rrule
+	}
+}
+
+// rruleEnd returns the UNTIL or COUNT part of the RRULE of a task. The event starts at
+// the current date of the task, so COUNT only includes the occurrences not done yet
+func rruleEnd(task Task, end RepeatEnd, loc *time.Location) string {
+	switch {
+	case end.Until != "" && task.Time == "":
+		return ";UNTIL=" + end.Until
+	case end.Until != "":
+		// UNTIL of a DATE-TIME event must be in UTC too
+		until, err := time.ParseInLocation(dateFormat+timeFormat, end.Until+task.Time, loc)
+		if err != nil {
+			return ""
+		}
+		return ";UNTIL=" + until.UTC().Format(icsTimestamp)
+	case end.Count > 0:
+		return ";COUNT=" + strconv.Itoa(max(end.Count-task.DoneCount, 1))
+	}
+	return ""
+}
+
+// joinInts formats numbers as a comma-separated list
+func joinInts(nums []int) string {
+	s := make([]string, len(nums))
+	for i, n := range nums {
+		s[i] = strconv.Itoa(n)
+	}
+	return strings.Join(s, ",")
+}
+
+// icsEscape escapes a TEXT value as required by RFC 5545
+func icsEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `;`, `\;`, `,`, `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// icsLine writes a content line, folding it at 75 octets without splitting UTF-8 characters
+func icsLine(b *strings.Builder, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(line[:cut] + "\r\n ")
+		line = line[cut:]
+		// Continuation lines start with a space
+		limit = 74
+	}
+	b.WriteString(line + "\r\n")
+}
+
+// writeEvent renders a task as a VEVENT: tasks without a time become all-day
+// events, timed tasks start at their time in loc and last for their duration
+func writeEvent(b *strings.Builder, task Task, stamp string, loc *time.Location) {
+	icsLine(b, "BEGIN:VEVENT")
+	icsLine(b, "UID:task-"+task.ID+"@go_final_project")
+	icsLine(b, "DTSTAMP:"+stamp)
+	if task.Time == "" {
+		icsLine(b, "DTSTART;VALUE=DATE:"+task.Date)
+		if date, err := time.Parse(dateFormat, task.Date); err == nil {
+			icsLine(b, "DTEND;VALUE=DATE:"+date.AddDate(0, 0, 1).Format(dateFormat))
+		}
+	} else if start, err := time.ParseInLocation(dateFormat+timeFormat, task.Date+task.Time, loc); err == nil {
+		icsLine(b, "DTSTART:"+start.UTC().Format(icsTimestamp))
+		if task.Duration > 0 {
+			end := start.Add(time.Duration(task.Duration) * time.Minute)
+			icsLine(b, "DTEND:"+end.UTC().Format(icsTimestamp))
+		}
+	}
+	icsLine(b, "SUMMARY:"+icsEscape(task.Title))
+	if task.Comment != "" {
+		icsLine(b, "DESCRIPTION:"+icsEscape(task.Comment))
+	}
+	if task.Repeat != "" {
+		// Rules that fail to parse are exported as a single event
+		if rule, err := ParseRepeat(task.Repeat); err == nil {
+			icsLine(b, "RRULE:"+repeatToRRule(rule)+rruleEnd(task, rule.End, loc))
+		}
+	}
+	if p, ok := icsPriority[task.Priority]; ok {
+		icsLine(b, "PRIORITY:"+strconv.Itoa(p))
+	}
+	if len(task.Tags) > 0 {
+		tags := make([]string, len(task.Tags))
+		for i, tag := range task.Tags {
+			tags[i] = icsEscape(tag)
+		}
+		icsLine(b, "CATEGORIES:"+strings.Join(tags, ","))
+	}
+	icsLine(b, "END:VEVENT")
+}
+
+// calendarHandler handles GET /api/calendar.ics and exports all tasks as an iCalendar feed
+func calendarHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tasks, err := service.List(TaskFilter{})
+	if err != nil {
+		writeServiceError(w, err, "failed to fetch tasks")
+		return
+	}
+
+	var b strings.Builder
+	icsLine(&b, "BEGIN:VCALENDAR")
+	icsLine(&b, "VERSION:2.0")
+	icsLine(&b, "PRODID:-//go_final_project//scheduler//RU")
+	icsLine(&b, "CALSCALE:GREGORIAN")
+	stamp := time.Now().UTC().Format(icsTimestamp)
+	for _, task := range tasks {
+		writeEvent(&b, task, stamp, service.loc)
+	}
+	icsLine(&b, "END:VCALENDAR")
+
+	w.Header().Set("Content-Type", "text/calendar; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, b.String())
+}
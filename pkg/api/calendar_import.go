@@ -0,0 +1,328 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// icsLocalTimestamp is the iCalendar date-time format without the UTC marker
+const icsLocalTimestamp = "20060102T150405"
+
+// icsEvent holds the VEVENT properties the importer understands
+type icsEvent struct {
+	UID         string
+	Summary     string
+	Description string
+	DTStart     icsTime
+	DTEnd       icsTime
+	RRule       string
+}
+
+// icsTime is a DATE or DATE-TIME value with its TZID parameter, if any
+type icsTime struct {
+	Value string
+	TZID  string
+}
+
+// icsWeekdays maps iCalendar BYDAY codes to the day numbers of the 'w' rule
+var icsWeekdays = map[string]int{"MO": 1, "TU": 2, "WE": 3, "TH": 4, "FR": 5, "SA": 6, "SU": 7}
+
+// icsUnescape reverses the TEXT escaping of RFC 5545
+func icsUnescape(s string) string {
+	return strings.NewReplacer(`\\`, `\`, `\;`, `;`, `\,`, `,`, `\n`, "\n", `\N`, "\n").Replace(s)
+}
+
+// splitProperty splits a content line into its upper-cased name, its parameters
+// such as VALUE=DATE or quoted TZID values, and its value
+func splitProperty(line string) (string, string, string, bool) {
+	quoted := false
+	for i, c := range line {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case c == ':' && !quoted:
+			name, params, _ := strings.Cut(line[:i], ";")
+			return strings.ToUpper(name), params, line[i+1:], true
+		}
+	}
+	return "", "", "", false
+}
+
+// tzidParam returns the TZID parameter of a property, without quotes
+func tzidParam(params string) string {
+	for _, param := range strings.Split(params, ";") {
+		key, value, _ := strings.Cut(param, "=")
+		if strings.EqualFold(key, "TZID") {
+			return strings.Trim(value, `"`)
+		}
+	}
+	return ""
+}
+
+// parseICSTime converts a DTSTART or DTEND value into a time in loc and reports
+// whether it has a time of day. UTC values are converted to loc; values with a
+// TZID the server doesn't know and floating values are read as local to loc
+func parseICSTime(t icsTime, loc *time.Location) (time.Time, bool, error) {
+	if len(t.Value) == len(dateFormat) {
+		date, err := time.Parse(dateFormat, t.Value)
+		return date, false, err
+	}
+	if value, ok := strings.CutSuffix(t.Value, "Z"); ok {
+		start, err := time.Parse(icsLocalTimestamp, value)
+		return start.In(loc), true, err
+	}
+	zone := loc
+	if t.TZID != "" {
+		if tz, err := time.LoadLocation(t.TZID); err == nil {
+			zone = tz
+		}
+	}
+	start, err := time.ParseInLocation(icsLocalTimestamp, t.Value, zone)
+	return start.In(loc), true, err
+}
+
+// parseICS extracts the VEVENTs of an iCalendar file
+func parseICS(data []byte) ([]icsEvent, error) {
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	// Unfold long lines: a line starting with a space or tab continues the previous one
+	text = strings.ReplaceAll(text, "\n ", "")
+	text = strings.ReplaceAll(text, "\n\t", "")
+
+	var events []icsEvent
+	var event *icsEvent
+	nested := 0 // depth of components such as VALARM inside the current event
+	for _, line := range strings.Split(text, "\n") {
+		name, params, value, ok := splitProperty(strings.TrimRight(line, "\r"))
+		if !ok {
+			continue
+		}
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VEVENT") && event == nil:
+			event = &icsEvent{}
+		case event == nil:
+		case name == "BEGIN":
+			nested++
+		case name == "END" && nested > 0:
+			nested--
+		case name == "END" && strings.EqualFold(value, "VEVENT"):
+			events = append(events, *event)
+			event = nil
+		case nested > 0:
+		case name == "UID":
+			event.UID = value
+		case name == "SUMMARY":
+			event.Summary = icsUnescape(value)
+		case name == "DESCRIPTION":
+			event.Description = icsUnescape(value)
+		case name == "DTSTART":
+			event.DTStart = icsTime{Value: value, TZID: tzidParam(params)}
+		case name == "DTEND":
+			event.DTEnd = icsTime{Value: value, TZID: tzidParam(params)}
+		case name == "RRULE":
+			event.RRule = value
+		}
+	}
+	if event != nil {
+		return nil, fmt.Errorf("unterminated VEVENT")
+	}
+	if events == nil && !strings.Contains(text, "BEGIN:VCALENDAR") {
+		return nil, fmt.Errorf("not an iCalendar file")
+	}
+	return events, nil
+}
+
+// rruleToRepeat converts a simple RRULE into a repeat rule understood by NextDate;
+// UNTIL and COUNT become its "until" or "count" end condition, with the date of
+// UNTIL taken in loc
+func rruleToRepeat(rrule string, loc *time.Location) (string, error) {
+	parts := make(map[string]string)
+	for _, part := range strings.Split(rrule, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return "", fmt.Errorf("invalid RRULE part: %s", part)
+		}
+		parts[strings.ToUpper(key)] = strings.ToUpper(value)
+	}
+
+	var end string
+	until, hasUntil := parts["UNTIL"]
+	count, hasCount := parts["COUNT"]
+	switch {
+	case hasUntil && hasCount:
+		return "", fmt.Errorf("RRULE can't have both UNTIL and COUNT")
+	case hasUntil:
+		// A DATE-TIME UNTIL is usually in UTC, which may be another day in loc
+		t, _, err := parseICSTime(icsTime{Value: until}, loc)
+		if err != nil {
+			return "", fmt.Errorf("invalid RRULE until: %s", until)
+		}
+		end = " until " + t.Format(dateFormat)
+	case hasCount:
+		end = " count " + count
+	}
+	delete(parts, "UNTIL")
+	delete(parts, "COUNT")
+
+	repeat, err := rruleFreqToRepeat(parts)
+	if err != nil {
+		return "", err
+	}
+	return repeat + end, nil
+}
+
+// rruleFreqToRepeat converts the FREQ and BY* parts of an RRULE into a repeat rule
+func rruleFreqToRepeat(parts map[string]string) (string, error) {
+	freq := parts["FREQ"]
+	allowed := map[string]bool{"FREQ": true, "INTERVAL": true, "WKST": true}
+	switch freq {
+	case "WEEKLY":
+		allowed["BYDAY"] = true
+	case "MONTHLY":
+		allowed["BYMONTHDAY"] = true
+		allowed["BYMONTH"] = true
+	}
+	for key := range parts {
+		if !allowed[key] {
+			return "", fmt.Errorf("unsupported RRULE part: %s", key)
+		}
+	}
+
+	interval := 1
+	if value, ok := parts["INTERVAL"]; ok {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return "", fmt.Errorf("invalid RRULE interval: %s", value)
+		}
+		interval = n
+	}
+
+	switch freq {
+	case "DAILY":
+		return fmt.Sprintf("d %d", interval), nil
+	case "YEARLY":
+		if interval != 1 {
+			return "", fmt.Errorf("unsupported yearly interval: %d", interval)
+		}
+		return "y", nil
+	case "WEEKLY":
+		byDay, ok := parts["BYDAY"]
+		if !ok {
+			// Every N weeks on the start weekday is the same as every 7*N days
+			return fmt.Sprintf("d %d", 7*interval), nil
+		}
+		if interval != 1 {
+			return "", fmt.Errorf("unsupported weekly interval with BYDAY: %d", interval)
+		}
+		var days []string
+		for _, code := range strings.Split(byDay, ",") {
+			day, ok := icsWeekdays[code]
+			if !ok {
+				return "", fmt.Errorf("unsupported BYDAY value: %s", code)
+			}
+			days = append(days, strconv.Itoa(day))
+		}
+		return "w " + strings.Join(days, ","), nil
+	case "MONTHLY":
+		byMonthDay, ok := parts["BYMONTHDAY"]
+		if !ok || interval != 1 {
+			return "", fmt.Errorf("unsupported monthly rule: only BYMONTHDAY every month is supported")
+		}
+		repeat := "m " + byMonthDay
+		if byMonth, ok := parts["BYMONTH"]; ok {
+			repeat += " " + byMonth
+		}
+		return repeat, nil
+	case "":
+		return "", fmt.Errorf("RRULE has no FREQ")
+	default:
+		return "", fmt.Errorf("unsupported RRULE frequency: %s", freq)
+	}
+}
+
+// eventToTask maps a VEVENT to a task checked by the service; DATE-TIME events
+// become timed tasks in the service's time zone
+func eventToTask(event icsEvent) (Task, error) {
+	task := Task{Title: event.Summary, Comment: event.Description}
+
+	if event.DTStart.Value == "" {
+		return task, fmt.Errorf("missing DTSTART")
+	}
+	start, timed, err := parseICSTime(event.DTStart, service.loc)
+	if err != nil {
+		return task, fmt.Errorf("invalid DTSTART: %s", event.DTStart.Value)
+	}
+	task.Date = start.Format(dateFormat)
+	if timed {
+		task.Time = start.Format(timeFormat)
+		if event.DTEnd.Value != "" {
+			end, _, err := parseICSTime(event.DTEnd, service.loc)
+			if err != nil {
+				return task, fmt.Errorf("invalid DTEND: %s", event.DTEnd.Value)
+			}
+			task.Duration = int(end.Sub(start).Minutes())
+		}
+	}
+
+	if event.RRule != "" {
+		task.Repeat, err = rruleToRepeat(event.RRule, service.loc)
+		if err != nil {
+			return task, err
+		}
+	}
+
+	return task, service.Check(&task)
+}
+
+// importICSHandler handles POST /api/import/ics: every VEVENT becomes a task and
+// events that can't be converted are reported in errors instead of being imported
+func importICSHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	data, err := readUpload(w, r)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	events, err := parseICS(data)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := ImportResponse{IDs: []string{}, Errors: []ImportError{}}
+	var tasks []Task
+	for i, event := range events {
+		task, err := eventToTask(event)
+		if err != nil {
+			resp.Errors = append(resp.Errors, ImportError{
+				Index: i + 1,
+				UID:   event.UID,
+				Title: event.Summary,
+				Error: err.Error(),
+				Code:  errorCode(err),
+			})
+			continue
+		}
+		tasks = append(tasks, task)
+	}
+
+	if len(tasks) > 0 {
+		resp.IDs, err = service.importChecked(tasks)
+		if err != nil {
+			writeServiceError(w, err, "failed to save tasks")
+			return
+		}
+	}
+	resp.Imported = len(resp.IDs)
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,24 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// doneHandler handles POST /api/task/done?id=ID: one-off tasks are deleted,
+// repeating tasks are moved to their next date or finished when their rule ends
+func doneHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := service.Done(r.FormValue("id")); err != nil {
+		writeServiceError(w, err, "failed to complete task")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{})
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// csvHeader lists the columns of CSV exports; imports match columns by these names
+var csvHeader = []string{"id", "date", "title", "comment", "repeat", "priority", "tags", "time", "duration", "done_count"}
+
+// exportHandler handles GET /api/export?format=csv|json and returns the whole scheduler table
+func exportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	format := r.FormValue("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" {
+		writeError(w, "invalid format: must be csv or json", http.StatusBadRequest)
+		return
+	}
+
+	tasks, err := service.List(TaskFilter{})
+	if err != nil {
+		writeServiceError(w, err, "failed to fetch tasks")
+		return
+	}
+
+	w.Header().Set("Content-Disposition", `attachment; filename="scheduler.`+format+`"`)
+	if format == "json" {
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string][]Task{"tasks": tasks})
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	cw := csv.NewWriter(w)
+	cw.Write(csvHeader)
+	for _, task := range tasks {
+		cw.Write([]string{
+			task.ID,
+			task.Date,
+			task.Title,
+			task.Comment,
+			task.Repeat,
+			strconv.Itoa(task.Priority),
+			strings.Join(task.Tags, ","),
+			task.Time,
+			strconv.Itoa(task.Duration),
+			strconv.Itoa(task.DoneCount),
+		})
+	}
+	cw.Flush()
+}
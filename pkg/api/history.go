@@ -0,0 +1,32 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Completion records one completion of a task
+type Completion struct {
+	// Date is the date the task was scheduled for, YYYYMMDD
+	Date string `json:"date" db:"date"`
+	// CompletedAt is the RFC 3339 time the task was actually marked as done
+	CompletedAt string `json:"completed_at" db:"completed_at"`
+}
+
+// historyHandler handles GET /api/task/history?id=ID to list the completions of a task
+func historyHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	completions, err := service.History(r.FormValue("id"))
+	if err != nil {
+		writeServiceError(w, err, "failed to fetch task history")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string][]Completion{"completions": completions})
+}
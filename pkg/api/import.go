@@ -0,0 +1,229 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxImportSize limits the size of uploaded import files
+const maxImportSize = 10 << 20
+
+// ImportError describes an event or row that couldn't be imported; Index counts from 1
+type ImportError struct {
+	Index int       `json:"index"`
+	UID   string    `json:"uid,omitempty"`
+	Title string    `json:"title,omitempty"`
+	Error string    `json:"error"`
+	Code  ErrorCode `json:"code,omitempty"`
+}
+
+// ImportResponse is the body returned by the import endpoints; Error is set
+// when the import was rejected as a whole
+type ImportResponse struct {
+	Imported int           `json:"imported"`
+	IDs      []string      `json:"ids"`
+	Errors   []ImportError `json:"errors"`
+	DryRun   bool          `json:"dry_run,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// readUpload returns the uploaded file from the "file" field of a multipart form
+// or, for any other content type, the raw request body
+func readUpload(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			return nil, fmt.Errorf("file is required")
+		}
+		defer file.Close()
+		return io.ReadAll(file)
+	}
+	return io.ReadAll(r.Body)
+}
+
+// importRow is a decoded row together with the error that prevented decoding it
+type importRow struct {
+	task Task
+	err  error
+}
+
+// decodeCSV reads tasks from CSV with a header row naming the columns as in csvHeader.
+// The id column is ignored: imported tasks always get new ids
+func decodeCSV(data []byte) ([]importRow, error) {
+	cr := csv.NewReader(bytes.NewReader(data))
+	cr.FieldsPerRecord = -1
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %v", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("CSV header is required")
+	}
+
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, fmt.Errorf("CSV header must contain a title column")
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	rows := make([]importRow, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := importRow{task: Task{
+			Date:    field(record, "date"),
+			Title:   field(record, "title"),
+			Comment: field(record, "comment"),
+			Repeat:  field(record, "repeat"),
+		}}
+		if priority := field(record, "priority"); priority != "" {
+			row.task.Priority, err = strconv.Atoi(priority)
+			if err != nil {
+				row.err = fmt.Errorf("invalid priority: %s", priority)
+			}
+		}
+		row.task.Time = field(record, "time")
+		if duration := field(record, "duration"); duration != "" && row.err == nil {
+			row.task.Duration, err = strconv.Atoi(duration)
+			if err != nil {
+				row.err = fmt.Errorf("invalid duration: %s", duration)
+			}
+		}
+		if doneCount := field(record, "done_count"); doneCount != "" && row.err == nil {
+			row.task.DoneCount, err = strconv.Atoi(doneCount)
+			if err != nil {
+				row.err = fmt.Errorf("invalid done_count: %s", doneCount)
+			}
+		}
+		if tags := field(record, "tags"); tags != "" {
+			row.task.Tags = strings.Split(tags, ",")
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// decodeJSON reads tasks from the {"tasks": [...]} export format or a bare array.
+// Ids are ignored: imported tasks always get new ids
+func decodeJSON(data []byte) ([]importRow, error) {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		var wrapped struct {
+			Tasks []json.RawMessage `json:"tasks"`
+		}
+		if err := json.Unmarshal(data, &wrapped); err != nil {
+			return nil, fmt.Errorf("invalid JSON")
+		}
+		raw = wrapped.Tasks
+	}
+
+	rows := make([]importRow, 0, len(raw))
+	for _, item := range raw {
+		var row importRow
+		if err := json.Unmarshal(item, &row.task); err != nil {
+			row.err = fmt.Errorf("invalid JSON")
+		}
+		row.task.ID = ""
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// importHandler handles POST /api/import?format=csv|json[&dry_run=1]. Every row is
+// checked like in taskHandler and the rows are saved in one transaction only if all
+// of them are valid; a dry run just reports the rows that would fail
+func importHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Read options from the URL so a multipart body isn't parsed before readUpload
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = "json"
+		if strings.Contains(r.Header.Get("Content-Type"), "csv") {
+			format = "csv"
+		}
+	}
+	dryRun, _ := strconv.ParseBool(query.Get("dry_run"))
+
+	data, err := readUpload(w, r)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var rows []importRow
+	switch format {
+	case "csv":
+		rows, err = decodeCSV(data)
+	case "json":
+		rows, err = decodeJSON(data)
+	default:
+		err = fmt.Errorf("invalid format: must be csv or json")
+	}
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := ImportResponse{IDs: []string{}, Errors: []ImportError{}, DryRun: dryRun}
+	for i := range rows {
+		row := &rows[i]
+		if row.err == nil {
+			row.err = service.Check(&row.task)
+		}
+		if row.err != nil {
+			resp.Errors = append(resp.Errors, ImportError{
+				Index: i + 1,
+				Title: row.task.Title,
+				Error: row.err.Error(),
+				Code:  errorCode(row.err),
+			})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	if dryRun {
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(resp)
+		return
+	}
+	if len(resp.Errors) > 0 {
+		resp.Error = fmt.Sprintf("%d of %d rows failed validation, nothing was imported", len(resp.Errors), len(rows))
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(resp)
+		return
+	}
+
+	tasks := make([]Task, len(rows))
+	for i, row := range rows {
+		tasks[i] = row.task
+	}
+	if len(tasks) > 0 {
+		resp.IDs, err = service.importChecked(tasks)
+		if err != nil {
+			writeServiceError(w, err, "failed to save tasks")
+			return
+		}
+	}
+	resp.Imported = len(resp.IDs)
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
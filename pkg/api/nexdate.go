@@ -1,56 +1,94 @@
 package api
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
-	"strings"
 	"time"
 )
 
 const dateFormat = "20060102"
 
-// NextDate calculates the next date for a task based on the repeat rule
+// timeFormat is the format of the optional time of day of a task
+const timeFormat = "15:04"
+
+const (
+	defaultPreviewCount = 10  // dates returned by /api/nextdate/preview when n is not given
+	maxPreviewCount     = 100 // upper bound on n in /api/nextdate/preview
+)
+
+// maxMonthlyScan bounds the day-by-day search for 'm' rules that can never match (e.g. "m 31 2")
+const maxMonthlyScan = 366 * 8
+
+// dateOf returns the calendar date of t in its own location as midnight UTC,
+// the form time.Parse gives to dateFormat values, so dates compare and add
+// days without DST surprises
+func dateOf(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// NextDate calculates the next date for a task based on the repeat rule.
+// Only the calendar date of now in its location matters, so callers pass
+// now in the time zone that decides which day is today. A rule ending with
+// "until YYYYMMDD" has no next date after that day, which is reported as an
+// empty date; "count N" is left to the caller, who knows how many occurrences
+// have passed
 func NextDate(now time.Time, dstart string, repeat string) (string, error) {
 	if repeat == "" {
 		return "", nil
 	}
-
-	start, err := time.Parse(dateFormat, dstart)
-	if err != nil {
+	if _, err := time.Parse(dateFormat, dstart); err != nil {
 		return "", fmt.Errorf("invalid date format: %v", err)
 	}
+	rule, err := ParseRepeat(repeat)
+	if err != nil {
+		return "", err
+	}
+	return rule.Next(now, dstart)
+}
 
-	parts := strings.SplitN(repeat, " ", 2)
-	rule := parts[0]
+// Occurrences returns up to n dates on which a task starting on dstart falls,
+// scheduled the way TaskService does it at now: the start date unless it is in
+// the past, then the next dates of the rule one after another. Fewer dates are
+// returned when the rule ends with "until" or "count"; a task without a repeat
+// rule happens just once
+func Occurrences(now time.Time, dstart string, repeat string, n int) ([]string, error) {
+	if _, err := time.Parse(dateFormat, dstart); err != nil {
+		return nil, fmt.Errorf("invalid date format: %v", err)
+	}
+	today := dateOf(now).Format(dateFormat)
+	if repeat == "" {
+		return []string{max(dstart, today)}, nil
+	}
+	rule, err := ParseRepeat(repeat)
+	if err != nil {
+		return nil, err
+	}
 
-	switch {
-	case rule == "y":
-		// Annual repetition: always add at least one year
-		start = start.AddDate(1, 0, 0)
-		// Continue adding years until strictly after now
-		for !start.After(now) {
-			start = start.AddDate(1, 0, 0)
-		}
-	case strings.HasPrefix(rule, "d"):
-		if len(parts) < 2 {
-			return "", fmt.Errorf("missing interval for 'd' rule")
+	// Find the first date after a past start, as when the task is saved
+	date := dstart
+	if date < today {
+		date, err = rule.Next(now, dstart)
+		if err != nil {
+			return nil, err
 		}
-		interval, err := strconv.Atoi(strings.TrimSpace(parts[1]))
-		if err != nil || interval < 1 || interval > 400 {
-			return "", fmt.Errorf("invalid interval: must be 1-400")
+	}
+
+	dates := []string{}
+	for date != "" && len(dates) < n {
+		if (rule.End.Until != "" && date > rule.End.Until) || (rule.End.Count > 0 && len(dates) == rule.End.Count) {
+			break
 		}
-		// Always add at least one interval
-		start = start.AddDate(0, 0, interval)
-		// Continue adding intervals until strictly after now
-		for !start.After(now) {
-			start = start.AddDate(0, 0, interval)
+		dates = append(dates, date)
+		// The next occurrence is the next date of the rule after this one
+		current, _ := time.Parse(dateFormat, date)
+		date, err = rule.Next(current, date)
+		if err != nil {
+			return nil, err
 		}
-	default:
-		return "", fmt.Errorf("unsupported repeat rule: %s", rule)
 	}
-
-	return start.Format(dateFormat), nil
+	return dates, nil
 }
 
 // nextDateHandler handles GET /api/nextdate?now=YYYYMMDD&date=YYYYMMDD&repeat=rule
@@ -59,24 +97,24 @@ func nextDateHandler(w http.ResponseWriter, r *http.Request) {
 	dateStr := r.FormValue("date")
 	repeat := r.FormValue("repeat")
 
-	now := time.Now()
+	now := service.localNow()
 	if nowStr != "" {
 		parsedNow, err := time.Parse(dateFormat, nowStr)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("invalid now date format: %v", err), http.StatusBadRequest)
+			writeError(w, fmt.Sprintf("invalid now date format: %v", err), http.StatusBadRequest)
 			return
 		}
 		now = parsedNow
 	}
 
 	if dateStr == "" {
-		http.Error(w, "date parameter is required", http.StatusBadRequest)
+		writeError(w, "date parameter is required", http.StatusBadRequest)
 		return
 	}
 
 	next, err := NextDate(now, dateStr, repeat)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeRequestError(w, err)
 		return
 	}
 
@@ -84,3 +122,47 @@ func nextDateHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	fmt.Fprint(w, next)
 }
+
+// previewHandler handles GET /api/nextdate/preview?now=YYYYMMDD&date=YYYYMMDD&repeat=rule&n=10
+// and returns the upcoming dates of a task as {"dates": [...]} before it is saved
+func previewHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	now := service.localNow()
+	if nowStr := r.FormValue("now"); nowStr != "" {
+		parsedNow, err := time.Parse(dateFormat, nowStr)
+		if err != nil {
+			writeError(w, "invalid now date format", http.StatusBadRequest)
+			return
+		}
+		now = parsedNow
+	}
+
+	n := defaultPreviewCount
+	if nStr := r.FormValue("n"); nStr != "" {
+		parsed, err := strconv.Atoi(nStr)
+		if err != nil || parsed < 1 {
+			writeError(w, "invalid n", http.StatusBadRequest)
+			return
+		}
+		n = min(parsed, maxPreviewCount)
+	}
+
+	date := r.FormValue("date")
+	if date == "" {
+		date = now.Format(dateFormat)
+	}
+
+	dates, err := Occurrences(now, date, r.FormValue("repeat"), n)
+	if err != nil {
+		writeRequestError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string][]string{"dates": dates})
+}
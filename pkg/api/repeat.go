@@ -0,0 +1,272 @@
+package api
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxInterval is the largest number of days between dates of a 'd' rule
+const maxInterval = 400
+
+// RepeatKind is the letter a repeat rule starts with
+type RepeatKind string
+
+const (
+	RepeatDaily   RepeatKind = "d" // every Interval days
+	RepeatYearly  RepeatKind = "y" // every year on the same day
+	RepeatWeekly  RepeatKind = "w" // on the listed days of the week
+	RepeatMonthly RepeatKind = "m" // on the listed days of the listed months
+)
+
+// ErrorCode identifies the reason an error response was returned, so clients
+// can show their own messages
+type ErrorCode string
+
+const (
+	CodeUnknownRule     ErrorCode = "unknown_rule"       // the rule doesn't start with d, y, w or m
+	CodeMissingArgument ErrorCode = "missing_argument"   // the rule lacks its interval or days
+	CodeBadFormat       ErrorCode = "bad_format"         // the rule has extra or malformed parts
+	CodeBadInterval     ErrorCode = "bad_interval"       // the interval of a 'd' rule isn't 1-400
+	CodeDayOutOfRange   ErrorCode = "day_out_of_range"   // a day of the week or month doesn't exist
+	CodeMonthOutOfRange ErrorCode = "month_out_of_range" // a month isn't 1-12
+	CodeBadEnd          ErrorCode = "bad_end"            // the until date or count is invalid
+	CodeNoMatch         ErrorCode = "no_match"           // a 'm' rule never matches, e.g. "m 31 2"
+	CodeRuleEnded       ErrorCode = "rule_ended"         // the rule has no dates left after today
+)
+
+// RepeatError reports an invalid repeat rule
+type RepeatError struct {
+	Code    ErrorCode
+	Message string
+}
+
+func (e *RepeatError) Error() string { return e.Message }
+
+// repeatError builds a RepeatError with a formatted message
+func repeatError(code ErrorCode, format string, args ...any) error {
+	return &RepeatError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// RepeatEnd is the optional end condition of a repeat rule: "until YYYYMMDD"
+// stops after the given date, "count N" after N occurrences
+type RepeatEnd struct {
+	Until string // last possible date, YYYYMMDD
+	Count int    // total number of occurrences including the first one
+}
+
+// RepeatRule is a parsed repeat rule. Lists keep the order they were written
+// in, so ParseRepeat(r.String()) gives r back
+type RepeatRule struct {
+	Kind      RepeatKind
+	Interval  int   // days between dates of a 'd' rule
+	Weekdays  []int // days of a 'w' rule, 1 is Monday and 7 is Sunday
+	MonthDays []int // days of a 'm' rule, 1-31, or -1 and -2 for the last and second to last day
+	Months    []int // months of a 'm' rule, 1-12; empty means every month
+	End       RepeatEnd
+}
+
+// ParseRepeat parses a repeat rule such as "d 7", "y", "w 1,3", "m -1 1,6" or
+// "d 7 count 10"; invalid rules are reported with a *RepeatError
+func ParseRepeat(repeat string) (RepeatRule, error) {
+	var r RepeatRule
+	fields := strings.Fields(joinLists(repeat))
+	if len(fields) == 0 {
+		return r, repeatError(CodeMissingArgument, "repeat rule is empty")
+	}
+
+	// A trailing "until YYYYMMDD" or "count N" is the end condition
+	if n := len(fields); n >= 3 {
+		switch fields[n-2] {
+		case "until":
+			if _, err := time.Parse(dateFormat, fields[n-1]); err != nil {
+				return r, repeatError(CodeBadEnd, "invalid until date: must be YYYYMMDD")
+			}
+			r.End.Until = fields[n-1]
+			fields = fields[:n-2]
+		case "count":
+			count, err := strconv.Atoi(fields[n-1])
+			if err != nil || count < 1 {
+				return r, repeatError(CodeBadEnd, "invalid count: must be a positive number")
+			}
+			r.End.Count = count
+			fields = fields[:n-2]
+		}
+	}
+
+	r.Kind = RepeatKind(fields[0])
+	args := fields[1:]
+	var err error
+	switch r.Kind {
+	case RepeatYearly:
+		if len(args) > 0 {
+			return r, repeatError(CodeBadFormat, "'y' rule takes no arguments")
+		}
+	case RepeatDaily:
+		if len(args) == 0 {
+			return r, repeatError(CodeMissingArgument, "missing interval for 'd' rule")
+		}
+		r.Interval, err = strconv.Atoi(args[0])
+		if err != nil || r.Interval < 1 || r.Interval > maxInterval {
+			return r, repeatError(CodeBadInterval, "invalid interval: must be 1-%d", maxInterval)
+		}
+		if len(args) > 1 {
+			return r, repeatError(CodeBadFormat, "invalid 'd' rule format")
+		}
+	case RepeatWeekly:
+		if len(args) == 0 {
+			return r, repeatError(CodeMissingArgument, "missing days for 'w' rule")
+		}
+		if len(args) > 1 {
+			return r, repeatError(CodeBadFormat, "invalid 'w' rule format")
+		}
+		r.Weekdays, err = parseList(args[0], func(day int) bool { return day >= 1 && day <= 7 },
+			CodeDayOutOfRange, "invalid day of week: must be 1-7")
+	case RepeatMonthly:
+		if len(args) == 0 {
+			return r, repeatError(CodeMissingArgument, "missing days for 'm' rule")
+		}
+		if len(args) > 2 {
+			return r, repeatError(CodeBadFormat, "invalid 'm' rule format")
+		}
+		r.MonthDays, err = parseList(args[0], func(day int) bool { return day >= -2 && day <= 31 && day != 0 },
+			CodeDayOutOfRange, "invalid day of month: must be 1-31, -1 or -2")
+		if err == nil && len(args) == 2 {
+			r.Months, err = parseList(args[1], func(month int) bool { return month >= 1 && month <= 12 },
+				CodeMonthOutOfRange, "invalid month: must be 1-12")
+		}
+	default:
+		return r, repeatError(CodeUnknownRule, "unsupported repeat rule: %s", fields[0])
+	}
+	return r, err
+}
+
+// joinLists removes the spaces around commas, so "w 1, 2" reads as "w 1,2"
+func joinLists(repeat string) string {
+	items := strings.Split(repeat, ",")
+	for i, item := range items {
+		items[i] = strings.TrimSpace(item)
+	}
+	return strings.Join(items, ",")
+}
+
+// parseList parses a comma-separated list of numbers accepted by valid
+func parseList(list string, valid func(int) bool, code ErrorCode, msg string) ([]int, error) {
+	var nums []int
+	for _, item := range strings.Split(list, ",") {
+		if item == "" {
+			return nil, repeatError(CodeBadFormat, "empty item in list: %s", list)
+		}
+		n, err := strconv.Atoi(item)
+		if err != nil || !valid(n) {
+			return nil, repeatError(code, "%s", msg)
+		}
+		nums = append(nums, n)
+	}
+	return nums, nil
+}
+
+// String formats the rule the way ParseRepeat reads it
+func (r RepeatRule) String() string {
+	s := string(r.Kind)
+	switch r.Kind {
+	case RepeatDaily:
+		s += " " + strconv.Itoa(r.Interval)
+	case RepeatWeekly:
+		s += " " + joinInts(r.Weekdays)
+	case RepeatMonthly:
+		s += " " + joinInts(r.MonthDays)
+		if len(r.Months) > 0 {
+			s += " " + joinInts(r.Months)
+		}
+	}
+	switch {
+	case r.End.Until != "":
+		s += " until " + r.End.Until
+	case r.End.Count > 0:
+		s += " count " + strconv.Itoa(r.End.Count)
+	}
+	return s
+}
+
+// Next returns the first date of the rule after both dstart and the date of
+// now in its location, or an empty date when the rule ends with "until" before
+// it. "count" is left to the caller, who knows how many occurrences have passed
+func (r RepeatRule) Next(now time.Time, dstart string) (string, error) {
+	start, err := time.Parse(dateFormat, dstart)
+	if err != nil {
+		return "", fmt.Errorf("invalid date format: %v", err)
+	}
+	now = dateOf(now)
+
+	switch r.Kind {
+	case RepeatYearly:
+		// Annual repetition: always add at least one year
+		start = start.AddDate(1, 0, 0)
+		// Continue adding years until strictly after now
+		for !start.After(now) {
+			start = start.AddDate(1, 0, 0)
+		}
+	case RepeatDaily:
+		// Always add at least one interval
+		start = start.AddDate(0, 0, r.Interval)
+		// Continue adding intervals until strictly after now
+		for !start.After(now) {
+			start = start.AddDate(0, 0, r.Interval)
+		}
+	case RepeatWeekly:
+		// Days before now can never match, so start scanning from now
+		if now.After(start) {
+			start = now
+		}
+		// Move day by day until a listed weekday strictly after now
+		start = start.AddDate(0, 0, 1)
+		for !start.After(now) || !r.matchWeekly(start) {
+			start = start.AddDate(0, 0, 1)
+		}
+	case RepeatMonthly:
+		// Days before now can never match, so start scanning from now
+		if now.After(start) {
+			start = now
+		}
+		// Move day by day until a listed day of a listed month strictly after now
+		found := false
+		for i := 0; i < maxMonthlyScan; i++ {
+			start = start.AddDate(0, 0, 1)
+			if start.After(now) && r.matchMonthly(start) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return "", repeatError(CodeNoMatch, "no date matches 'm' rule: %s", r)
+		}
+	default:
+		return "", repeatError(CodeUnknownRule, "unsupported repeat rule: %s", r.Kind)
+	}
+
+	next := start.Format(dateFormat)
+	if r.End.Until != "" && next > r.End.Until {
+		return "", nil
+	}
+	return next, nil
+}
+
+// matchWeekly reports whether date falls on one of the days of a 'w' rule
+func (r RepeatRule) matchWeekly(date time.Time) bool {
+	// time.Weekday counts from Sunday = 0
+	return slices.Contains(r.Weekdays, (int(date.Weekday())+6)%7+1)
+}
+
+// matchMonthly reports whether date falls on one of the days of one of the months of a 'm' rule
+func (r RepeatRule) matchMonthly(date time.Time) bool {
+	if len(r.Months) > 0 && !slices.Contains(r.Months, int(date.Month())) {
+		return false
+	}
+	// Day 0 of the next month is the last day of the current one
+	last := time.Date(date.Year(), date.Month()+1, 0, 0, 0, 0, 0, date.Location()).Day()
+	day := date.Day()
+	return slices.Contains(r.MonthDays, day) || slices.Contains(r.MonthDays, day-last-1)
+}
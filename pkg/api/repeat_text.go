@@ -0,0 +1,224 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// languages are the languages of DescribeRepeat; the first one is the default
+var languages = []string{"ru", "en"}
+
+// ruWeekdays are the days of the week in the dative plural, "по понедельникам"; index 0 is Monday
+var ruWeekdays = [...]string{"понедельникам", "вторникам", "средам", "четвергам", "пятницам", "субботам", "воскресеньям"}
+
+// ruMonths are the months in the prepositional case, "в январе"; index 0 is January
+var ruMonths = [...]string{"январе", "феврале", "марте", "апреле", "мае", "июне",
+	"июле", "августе", "сентябре", "октябре", "ноябре", "декабре"}
+
+// ruPlural picks the Russian form for n out of the forms for 1, 2 and 5
+func ruPlural(n int, one, few, many string) string {
+	switch {
+	case n%10 == 1 && n%100 != 11:
+		return one
+	case n%10 >= 2 && n%10 <= 4 && (n%100 < 12 || n%100 > 14):
+		return few
+	}
+	return many
+}
+
+// enOrdinal formats 1 as "1st", 2 as "2nd" and so on
+func enOrdinal(n int) string {
+	suffix := "th"
+	switch {
+	case n%100 >= 11 && n%100 <= 13:
+	case n%10 == 1:
+		suffix = "st"
+	case n%10 == 2:
+		suffix = "nd"
+	case n%10 == 3:
+		suffix = "rd"
+	}
+	return strconv.Itoa(n) + suffix
+}
+
+// joinWords joins a list as "a, b and c", with and being the word for "and"
+func joinWords(words []string, and string) string {
+	if len(words) < 2 {
+		return strings.Join(words, "")
+	}
+	return strings.Join(words[:len(words)-1], ", ") + " " + and + " " + words[len(words)-1]
+}
+
+// DescribeRepeat describes a repeat rule for people, e.g. "каждые 14 дней" or
+// "every 14 days". lang is "ru" or "en"; other languages get Russian
+func DescribeRepeat(rule RepeatRule, lang string) string {
+	if lang == "en" {
+		return describeEnglish(rule)
+	}
+	return describeRussian(rule)
+}
+
+func describeRussian(rule RepeatRule) string {
+	var s string
+	switch rule.Kind {
+	case RepeatYearly:
+		s = "ежегодно"
+	case RepeatDaily:
+		if rule.Interval == 1 {
+			s = "ежедневно"
+		} else {
+			s = ruPlural(rule.Interval, "каждый", "каждые", "каждые") + " " + strconv.Itoa(rule.Interval) + " " +
+				ruPlural(rule.Interval, "день", "дня", "дней")
+		}
+	case RepeatWeekly:
+		days := make([]string, len(rule.Weekdays))
+		for i, day := range rule.Weekdays {
+			days[i] = ruWeekdays[day-1]
+		}
+		s = "по " + joinWords(days, "и")
+	case RepeatMonthly:
+		days := make([]string, len(rule.MonthDays))
+		for i, day := range rule.MonthDays {
+			switch day {
+			case -1:
+				days[i] = "последнего"
+			case -2:
+				days[i] = "предпоследнего"
+			default:
+				days[i] = strconv.Itoa(day)
+			}
+		}
+		s = joinWords(days, "и") + " числа "
+		if len(rule.Months) == 0 {
+			s += "каждого месяца"
+		} else {
+			months := make([]string, len(rule.Months))
+			for i, month := range rule.Months {
+				months[i] = ruMonths[month-1]
+			}
+			s += "в " + joinWords(months, "и")
+		}
+	}
+
+	switch {
+	case rule.End.Until != "":
+		until, _ := time.Parse(dateFormat, rule.End.Until)
+		s += " до " + until.Format(searchDateFormat)
+	case rule.End.Count > 0:
+		s += fmt.Sprintf(", %d %s", rule.End.Count, ruPlural(rule.End.Count, "раз", "раза", "раз"))
+	}
+	return s
+}
+
+func describeEnglish(rule RepeatRule) string {
+	var s string
+	switch rule.Kind {
+	case RepeatYearly:
+		s = "yearly"
+	case RepeatDaily:
+		if rule.Interval == 1 {
+			s = "daily"
+		} else {
+			s = fmt.Sprintf("every %d days", rule.Interval)
+		}
+	case RepeatWeekly:
+		days := make([]string, len(rule.Weekdays))
+		for i, day := range rule.Weekdays {
+			// time.Weekday counts from Sunday = 0
+			days[i] = time.Weekday(day % 7).String()
+		}
+		s = "every " + joinWords(days, "and")
+	case RepeatMonthly:
+		days := make([]string, len(rule.MonthDays))
+		for i, day := range rule.MonthDays {
+			switch day {
+			case -1:
+				days[i] = "last day"
+			case -2:
+				days[i] = "second to last day"
+			default:
+				days[i] = enOrdinal(day)
+			}
+		}
+		s = "on the " + joinWords(days, "and") + " of "
+		if len(rule.Months) == 0 {
+			s += "every month"
+		} else {
+			months := make([]string, len(rule.Months))
+			for i, month := range rule.Months {
+				months[i] = time.Month(month).String()
+			}
+			s += joinWords(months, "and")
+		}
+	}
+
+	switch {
+	case rule.End.Until != "":
+		until, _ := time.Parse(dateFormat, rule.End.Until)
+		s += " until " + until.Format("January 2, 2006")
+	case rule.End.Count > 0:
+		if rule.End.Count == 1 {
+			s += ", once"
+		} else {
+			s += fmt.Sprintf(", %d times", rule.End.Count)
+		}
+	}
+	return s
+}
+
+// preferredLanguage picks ru or en from an Accept-Language header such as
+// "en-US,en;q=0.9,ru;q=0.8", honouring the quality values. Without a match the
+// first language the client doesn't refuse with q=0 is used
+func preferredLanguage(header string) string {
+	lang, best := "", 0.0
+	refused := make(map[string]bool)
+	for _, item := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(item), ";")
+		primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if !slices.Contains(languages, primary) {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		// q=0 means the language is not acceptable at all
+		if q <= 0 {
+			refused[primary] = true
+			continue
+		}
+		if q > best {
+			lang, best = primary, q
+		}
+	}
+	if lang != "" {
+		return lang
+	}
+	for _, l := range languages {
+		if !refused[l] {
+			return l
+		}
+	}
+	return languages[0]
+}
+
+// describeTasks fills in RepeatText of the tasks in the language the client prefers
+func describeTasks(r *http.Request, tasks []Task) {
+	lang := preferredLanguage(r.Header.Get("Accept-Language"))
+	for i := range tasks {
+		if tasks[i].Repeat == "" {
+			continue
+		}
+		if rule, err := ParseRepeat(tasks[i].Repeat); err == nil {
+			tasks[i].RepeatText = DescribeRepeat(rule, lang)
+		}
+	}
+}
@@ -0,0 +1,350 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// ErrTaskNotFound is returned when no task has the requested id
+var ErrTaskNotFound = errors.New("task not found")
+
+// ValidationError reports a task or request rejected by the business rules of TaskService
+type ValidationError struct {
+	Err error
+}
+
+func (e *ValidationError) Error() string { return e.Err.Error() }
+
+func (e *ValidationError) Unwrap() error { return e.Err }
+
+// invalid wraps a message into a ValidationError
+func invalid(format string, args ...any) error {
+	return &ValidationError{Err: fmt.Errorf(format, args...)}
+}
+
+// TaskFilter selects, orders and pages the tasks returned by TaskStore.List
+type TaskFilter struct {
+	Date   string   // only tasks on this date, YYYYMMDD
+	Before string   // only tasks dated before this date, YYYYMMDD
+	Search string   // text the title or comment must contain, ignoring case
+	Tags   []string // normalised tags every task must have
+	Sort   string   // key of taskSorts; empty means date
+	Desc   bool     // sort direction of the Sort key
+	After  []any    // sortKeys values of the last task of the previous page
+	Limit  int      // maximum number of tasks; 0 means no limit
+	// Deleted lists the tasks in the trash instead of the active ones
+	Deleted bool
+}
+
+// TaskStore persists tasks. Stores don't validate tasks, that is the job of
+// TaskService; unknown ids are reported with ErrTaskNotFound. Tasks in the
+// trash are invisible to every method except Restore, Purge and List with
+// TaskFilter.Deleted; finished tasks, whose repeat rule has ended, are only
+// visible to History
+type TaskStore interface {
+	// Create saves a new task with its tags and returns its id
+	Create(task Task) (string, error)
+	// Import saves all tasks or none of them and returns their ids in order
+	Import(tasks []Task) ([]string, error)
+	// Get returns a task with its tags
+	Get(id string) (Task, error)
+	// Update replaces a task; nil Tags keep the current tags and DoneCount is never changed
+	Update(task Task) error
+	// Delete moves an active task to the trash, recording the time of deletion
+	Delete(id string, at time.Time) error
+	// Restore brings a task back from the trash
+	Restore(id string) error
+	// Purge permanently removes the tasks moved to the trash before the given
+	// time and returns their number
+	Purge(before time.Time) (int, error)
+	// Done reads a task, records its completion at the given time and applies
+	// next to it atomically: any date but an empty one moves the task there and
+	// increments its DoneCount. An empty date deletes a one-off task and
+	// finishes a repeating one, keeping it for History
+	Done(id string, at time.Time, next func(Task) (string, error)) error
+	// History returns the completions of a task, most recent first
+	History(id string) ([]Completion, error)
+	// List returns the tasks matching the filter with their tags
+	List(filter TaskFilter) ([]Task, error)
+	// Tags returns the tags in use with the number of tasks having them, ordered by name
+	Tags() ([]TagCount, error)
+}
+
+// TaskService applies the scheduler's rules to tasks kept in a TaskStore
+type TaskService struct {
+	store TaskStore
+	now   func() time.Time
+	loc   *time.Location // time zone that decides which day is today
+}
+
+// NewTaskService returns a service working with the given store in the local time zone
+func NewTaskService(store TaskStore) *TaskService {
+	return &TaskService{store: store, now: time.Now, loc: time.Local}
+}
+
+// LocationFromEnv returns the time zone named by TODO_TZ, e.g. "Europe/Moscow",
+// falling back to the local time zone of the server
+func LocationFromEnv() (*time.Location, error) {
+	name := os.Getenv("TODO_TZ")
+	if name == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TODO_TZ: %v", err)
+	}
+	return loc, nil
+}
+
+// SetLocation sets the time zone that decides which day is today
+func (s *TaskService) SetLocation(loc *time.Location) {
+	s.loc = loc
+}
+
+// localNow returns the current time in the service's time zone
+func (s *TaskService) localNow() time.Time {
+	return s.now().In(s.loc)
+}
+
+// today returns the current date in the service's time zone, YYYYMMDD
+func (s *TaskService) today() string {
+	return s.localNow().Format(dateFormat)
+}
+
+// Check validates a task before it is saved and normalises it: tags are
+// cleaned up, an empty date means today and a past date moves to today or,
+// for repeating tasks, to the next occurrence
+func (s *TaskService) Check(task *Task) error {
+	if err := checkTask(task, s.localNow()); err != nil {
+		return &ValidationError{Err: err}
+	}
+	return nil
+}
+
+// checkTask implements TaskService.Check for the given current time; today
+// is the date of now in its location
+func checkTask(task *Task, now time.Time) error {
+	if task.Title == "" {
+		return fmt.Errorf("title is required")
+	}
+
+	if task.Priority < 0 || task.Priority > maxPriority {
+		return fmt.Errorf("invalid priority: must be 0-%d", maxPriority)
+	}
+
+	tags, err := normalizeTags(task.Tags)
+	if err != nil {
+		return err
+	}
+	task.Tags = tags
+
+	if task.Time != "" {
+		t, err := time.Parse(timeFormat, task.Time)
+		if err != nil {
+			return fmt.Errorf("invalid time format: must be HH:MM")
+		}
+		task.Time = t.Format(timeFormat)
+	}
+	if task.Duration < 0 || task.Duration > maxDuration {
+		return fmt.Errorf("invalid duration: must be 0-%d minutes", maxDuration)
+	}
+	if task.Duration > 0 && task.Time == "" {
+		return fmt.Errorf("duration requires time")
+	}
+	if task.DoneCount < 0 {
+		return fmt.Errorf("invalid done_count: must not be negative")
+	}
+
+	// Set date to today if empty
+	today := now.Format(dateFormat)
+	if task.Date == "" {
+		task.Date = today
+	}
+
+	// Validate date format
+	if _, err := time.Parse(dateFormat, task.Date); err != nil {
+		return fmt.Errorf("invalid date format")
+	}
+
+	// Validate the repeat rule and store it in its canonical form; a past date
+	// moves to its next occurrence. YYYYMMDD dates compare as strings, with no
+	// time zone involved
+	if task.Repeat != "" {
+		rule, err := ParseRepeat(task.Repeat)
+		if err != nil {
+			return err
+		}
+		task.Repeat = rule.String()
+		if rule.End.Until != "" && rule.End.Until < task.Date {
+			return repeatError(CodeBadEnd, "until date is before the task date")
+		}
+		next, err := rule.Next(now, task.Date)
+		if err != nil {
+			return err
+		}
+		if task.Date < today {
+			if next == "" {
+				return repeatError(CodeRuleEnded, "repeat rule has no dates left after today")
+			}
+			task.Date = next
+		}
+	} else if task.Date < today {
+		// A past one-off task is due today
+		task.Date = today
+	}
+	return nil
+}
+
+// Create checks and saves a new task and returns its id
+func (s *TaskService) Create(task Task) (string, error) {
+	if err := s.Check(&task); err != nil {
+		return "", err
+	}
+	return s.store.Create(task)
+}
+
+// Import checks and saves all tasks in one go; if any task is invalid nothing is saved
+func (s *TaskService) Import(tasks []Task) ([]string, error) {
+	for i := range tasks {
+		if err := s.Check(&tasks[i]); err != nil {
+			return nil, invalid("task %d: %w", i+1, err)
+		}
+	}
+	return s.importChecked(tasks)
+}
+
+// importChecked saves tasks that have already passed Check, so the import
+// handlers, which check every row to report all errors, don't check them twice
+func (s *TaskService) importChecked(tasks []Task) ([]string, error) {
+	return s.store.Import(tasks)
+}
+
+// Get returns a task by id
+func (s *TaskService) Get(id string) (Task, error) {
+	if id == "" {
+		return Task{}, invalid("id is required")
+	}
+	return s.store.Get(id)
+}
+
+// Update checks and saves an existing task; nil Tags keep the current tags
+func (s *TaskService) Update(task Task) error {
+	if task.ID == "" {
+		return invalid("id is required")
+	}
+	if err := s.Check(&task); err != nil {
+		return err
+	}
+	return s.store.Update(task)
+}
+
+// Delete moves a task to the trash
+func (s *TaskService) Delete(id string) error {
+	if id == "" {
+		return invalid("id is required")
+	}
+	return s.store.Delete(id, s.now())
+}
+
+// Restore brings a task back from the trash
+func (s *TaskService) Restore(id string) error {
+	if id == "" {
+		return invalid("id is required")
+	}
+	return s.store.Restore(id)
+}
+
+// Trash returns the deleted tasks, most recently deleted first
+func (s *TaskService) Trash() ([]Task, error) {
+	tasks, err := s.List(TaskFilter{Deleted: true})
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].DeletedAt > tasks[j].DeletedAt
+	})
+	return tasks, err
+}
+
+// PurgeTrash permanently removes the tasks deleted more than retention ago
+func (s *TaskService) PurgeTrash(retention time.Duration) (int, error) {
+	return s.store.Purge(s.now().Add(-retention))
+}
+
+// Done completes a task: a one-off task is deleted and a repeating task
+// moves to the next date of its rule after its current date, keeping its time.
+// A repeating task whose rule has ended with its final occurrence is finished:
+// it leaves the schedule, but its history stays available
+func (s *TaskService) Done(id string) error {
+	if id == "" {
+		return invalid("id is required")
+	}
+	now := s.localNow()
+	return s.store.Done(id, now, func(task Task) (string, error) {
+		if task.Repeat == "" {
+			return "", nil
+		}
+		rule, err := ParseRepeat(task.Repeat)
+		if err != nil {
+			return "", fmt.Errorf("invalid repeat rule of task %s: %w", task.ID, err)
+		}
+		if rule.End.Count > 0 && task.DoneCount+1 >= rule.End.Count {
+			return "", nil
+		}
+		next, err := rule.Next(now, task.Date)
+		if err != nil {
+			return "", fmt.Errorf("invalid repeat rule of task %s: %w", task.ID, err)
+		}
+		return next, nil
+	})
+}
+
+// History returns the completions of a task, most recent first
+func (s *TaskService) History(id string) ([]Completion, error) {
+	if id == "" {
+		return nil, invalid("id is required")
+	}
+	completions, err := s.store.History(id)
+	if completions == nil && err == nil {
+		completions = []Completion{}
+	}
+	return completions, err
+}
+
+// List returns the tasks matching the filter
+func (s *TaskService) List(filter TaskFilter) ([]Task, error) {
+	if filter.Sort == "" {
+		filter.Sort = "date"
+	}
+	if _, ok := taskSorts[filter.Sort]; !ok {
+		return nil, invalid("invalid sort: must be date, priority or title")
+	}
+	tasks, err := s.store.List(filter)
+	if tasks == nil && err == nil {
+		tasks = []Task{}
+	}
+	today := s.today()
+	for i := range tasks {
+		tasks[i].Overdue = tasks[i].DeletedAt == "" && tasks[i].Date < today
+	}
+	return tasks, err
+}
+
+// Overdue returns the tasks whose date has passed without them being done, oldest first
+func (s *TaskService) Overdue() ([]Task, error) {
+	return s.List(TaskFilter{Before: s.today()})
+}
+
+// Today returns the tasks due today
+func (s *TaskService) Today() ([]Task, error) {
+	return s.List(TaskFilter{Date: s.today()})
+}
+
+// Tags returns the tags in use with their task counts
+func (s *TaskService) Tags() ([]TagCount, error) {
+	tags, err := s.store.Tags()
+	if tags == nil && err == nil {
+		tags = []TagCount{}
+	}
+	return tags, err
+}
@@ -0,0 +1,654 @@
+package api
+
+import (
+	"errors"
+	"go_final_project/pkg/db"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+	_ "time/tzdata" // time zones for TestServiceTimeZone on hosts without zoneinfo
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testNow is the fixed current time of the service in these tests
+var testNow = time.Date(2024, 1, 26, 15, 0, 0, 0, time.UTC)
+
+// newTestService returns a service over store whose clock is stopped at now, in UTC
+func newTestService(store TaskStore, now time.Time) *TaskService {
+	s := NewTaskService(store)
+	s.now = func() time.Time { return now }
+	s.SetLocation(time.UTC)
+	return s
+}
+
+// stores returns every TaskStore implementation so they are held to the same rules
+func stores(t *testing.T) map[string]TaskStore {
+	require.NoError(t, db.Init(filepath.Join(t.TempDir(), "scheduler.db")))
+	conn := db.DB
+	t.Cleanup(func() { conn.Close() })
+	return map[string]TaskStore{
+		"memory": NewMemoryStore(),
+		"sqlite": NewSQLiteStore(db.DB),
+	}
+}
+
+func TestCheckTask(t *testing.T) {
+	tbl := []struct {
+		date, repeat string
+		want         string
+	}{
+		{"", "", "20240126"},
+		{"20240120", "", "20240126"},
+		{"20240126", "d 5", "20240126"},
+		{"20240120", "d 5", "20240130"},
+		{"20240301", "w 1", "20240301"},
+		{"20230126", "y", "20250126"},
+	}
+	for _, v := range tbl {
+		task := Task{Title: "Задача", Date: v.date, Repeat: v.repeat}
+		assert.NoError(t, checkTask(&task, testNow), "%v", v)
+		assert.Equal(t, v.want, task.Date, "%v", v)
+	}
+
+	for _, task := range []Task{
+		{Date: "20240126"},
+		{Title: "Задача", Date: "26.01.2024"},
+		{Title: "Задача", Repeat: "x 1"},
+		{Title: "Задача", Priority: 4},
+		{Title: "Задача", Tags: []string{" "}},
+		{Title: "Задача", Time: "25:00"},
+		{Title: "Задача", Time: "полдень"},
+		{Title: "Задача", Duration: 30},
+		{Title: "Задача", Time: "10:00", Duration: -5},
+		{Title: "Задача", Time: "10:00", Duration: maxDuration + 1},
+		{Title: "Задача", Repeat: "d 7 until 2024"},
+		{Title: "Задача", Repeat: "d 7 count 0"},
+		{Title: "Задача", Date: "20240130", Repeat: "d 7 until 20240129"},
+		// A past task whose repeat has ended can't move to a future date
+		{Title: "Задача", Date: "20240101", Repeat: "d 7 until 20240120"},
+	} {
+		assert.Error(t, checkTask(&task, testNow), "%v", task)
+	}
+}
+
+func TestServiceErrors(t *testing.T) {
+	s := newTestService(NewMemoryStore(), testNow)
+
+	var invalid *ValidationError
+	_, err := s.Create(Task{Title: "Задача", Repeat: "d 0"})
+	assert.True(t, errors.As(err, &invalid))
+	assert.True(t, errors.As(s.Update(Task{Title: "Задача"}), &invalid))
+	assert.True(t, errors.As(s.Done(""), &invalid))
+
+	assert.ErrorIs(t, s.Update(Task{ID: "42", Title: "Задача"}), ErrTaskNotFound)
+	assert.ErrorIs(t, s.Delete("abc"), ErrTaskNotFound)
+	_, err = s.Get("42")
+	assert.ErrorIs(t, err, ErrTaskNotFound)
+}
+
+// listTitles returns a function that lists the titles of the tasks it gets,
+// failing the test on the error that came with them
+func listTitles(t *testing.T) func([]Task, error) []string {
+	return func(tasks []Task, err error) []string {
+		require.NoError(t, err)
+		var titles []string
+		for _, task := range tasks {
+			titles = append(titles, task.Title)
+		}
+		return titles
+	}
+}
+
+// TestService runs every case against a fresh service over each TaskStore
+// implementation. The tasks of a case are saved first and their ids passed on
+func TestService(t *testing.T) {
+	tbl := []struct {
+		name  string
+		tasks []Task
+		test  func(t *testing.T, s *TaskService, ids []string)
+	}{
+		{"done", []Task{
+			{Title: "Один раз"},
+			{Title: "По понедельникам", Date: "20240126", Repeat: "w 1"},
+		}, func(t *testing.T, s *TaskService, ids []string) {
+			once, weekly := ids[0], ids[1]
+			require.NoError(t, s.Done(once))
+			_, err := s.Get(once)
+			assert.ErrorIs(t, err, ErrTaskNotFound)
+
+			for _, want := range []string{"20240129", "20240205", "20240212"} {
+				require.NoError(t, s.Done(weekly))
+				task, err := s.Get(weekly)
+				require.NoError(t, err)
+				assert.Equal(t, want, task.Date)
+			}
+			assert.ErrorIs(t, s.Done("100500"), ErrTaskNotFound)
+
+			history, err := s.History(weekly)
+			require.NoError(t, err)
+			assert.Equal(t, []Completion{
+				{Date: "20240205", CompletedAt: "2024-01-26T15:00:00Z"},
+				{Date: "20240129", CompletedAt: "2024-01-26T15:00:00Z"},
+				{Date: "20240126", CompletedAt: "2024-01-26T15:00:00Z"},
+			}, history)
+			_, err = s.History(once)
+			assert.ErrorIs(t, err, ErrTaskNotFound)
+		}},
+		{"finished series keeps history", []Task{
+			{Title: "Курс уколов", Date: "20240126", Repeat: "d 1 count 2", Tags: []string{"health"}},
+		}, func(t *testing.T, s *TaskService, ids []string) {
+			id := ids[0]
+			require.NoError(t, s.Done(id))
+			require.NoError(t, s.Done(id))
+
+			// The finished task leaves the schedule and can't be changed any more
+			_, err := s.Get(id)
+			assert.ErrorIs(t, err, ErrTaskNotFound)
+			assert.ErrorIs(t, s.Done(id), ErrTaskNotFound)
+			assert.ErrorIs(t, s.Delete(id), ErrTaskNotFound)
+			assert.Empty(t, listTitles(t)(s.List(TaskFilter{})))
+			assert.Empty(t, listTitles(t)(s.Trash()))
+			tags, err := s.Tags()
+			require.NoError(t, err)
+			assert.Empty(t, tags)
+
+			history, err := s.History(id)
+			require.NoError(t, err)
+			assert.Equal(t, []Completion{
+				{Date: "20240127", CompletedAt: "2024-01-26T15:00:00Z"},
+				{Date: "20240126", CompletedAt: "2024-01-26T15:00:00Z"},
+			}, history)
+		}},
+		{"concurrent done", []Task{
+			{Title: "Каждый день", Date: "20240126", Repeat: "d 1"},
+		}, func(t *testing.T, s *TaskService, ids []string) {
+			// Every click is applied once, none of them fails
+			const clicks = 10
+			errs := make(chan error, clicks)
+			var wg sync.WaitGroup
+			for range clicks {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					errs <- s.Done(ids[0])
+				}()
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				assert.NoError(t, err)
+			}
+			task, err := s.Get(ids[0])
+			require.NoError(t, err)
+			assert.Equal(t, "20240205", task.Date)
+			history, err := s.History(ids[0])
+			require.NoError(t, err)
+			assert.Len(t, history, clicks)
+		}},
+		{"update tags", []Task{
+			{Title: "Отчёт", Tags: []string{"Work", "urgent", "work"}},
+		}, func(t *testing.T, s *TaskService, ids []string) {
+			task, err := s.Get(ids[0])
+			require.NoError(t, err)
+			assert.Equal(t, []string{"urgent", "work"}, task.Tags)
+
+			// nil tags keep the current ones, an empty list removes them
+			task.Tags = nil
+			task.Title = "Квартальный отчёт"
+			require.NoError(t, s.Update(task))
+			task, err = s.Get(ids[0])
+			require.NoError(t, err)
+			assert.Equal(t, "Квартальный отчёт", task.Title)
+			assert.Equal(t, []string{"urgent", "work"}, task.Tags)
+
+			task.Tags = []string{}
+			require.NoError(t, s.Update(task))
+			task, err = s.Get(ids[0])
+			require.NoError(t, err)
+			assert.Empty(t, task.Tags)
+		}},
+		{"list", []Task{
+			{Title: "Бассейн", Date: "20240128", Priority: 1, Tags: []string{"sport"}},
+			{Title: "Отчёт", Date: "20240127", Priority: 3, Tags: []string{"work"}},
+			{Title: "Созвон", Date: "20240127", Comment: "про БАССЕЙН", Tags: []string{"work"}},
+			{Title: "Аптека", Date: "20240129", Priority: 3},
+		}, func(t *testing.T, s *TaskService, ids []string) {
+			titles := listTitles(t)
+			assert.Equal(t, []string{"Отчёт", "Созвон", "Бассейн", "Аптека"}, titles(s.List(TaskFilter{})))
+			assert.Equal(t, []string{"Отчёт", "Созвон"}, titles(s.List(TaskFilter{Date: "20240127"})))
+			assert.Equal(t, []string{"Созвон", "Бассейн"}, titles(s.List(TaskFilter{Search: "бассейн"})))
+			assert.Equal(t, []string{"Отчёт", "Созвон"}, titles(s.List(TaskFilter{Tags: []string{"work"}})))
+			assert.Equal(t, []string{"Отчёт", "Аптека", "Бассейн", "Созвон"},
+				titles(s.List(TaskFilter{Sort: "priority", Desc: true})))
+			assert.Equal(t, []string{"Аптека", "Бассейн", "Отчёт", "Созвон"}, titles(s.List(TaskFilter{Sort: "title"})))
+
+			// Page through by priority two tasks at a time
+			var pages []string
+			filter := TaskFilter{Sort: "priority", Desc: true, Limit: 2}
+			keys := sortKeys(filter.Sort, filter.Desc)
+			for {
+				tasks, err := s.List(filter)
+				require.NoError(t, err)
+				if len(tasks) == 0 {
+					break
+				}
+				for _, task := range tasks {
+					pages = append(pages, task.Title)
+				}
+				last := tasks[len(tasks)-1]
+				filter.After = nil
+				for _, k := range keys {
+					filter.After = append(filter.After, k.value(last))
+				}
+			}
+			assert.Equal(t, []string{"Отчёт", "Аптека", "Бассейн", "Созвон"}, pages)
+
+			_, err := s.List(TaskFilter{Sort: "color"})
+			var invalid *ValidationError
+			assert.True(t, errors.As(err, &invalid))
+
+			tags, err := s.Tags()
+			require.NoError(t, err)
+			assert.Equal(t, []TagCount{{"sport", 1}, {"work", 2}}, tags)
+		}},
+		{"trash", []Task{
+			{Title: "Удалить", Tags: []string{"trash"}},
+			{Title: "Оставить"},
+		}, func(t *testing.T, s *TaskService, ids []string) {
+			id, keep := ids[0], ids[1]
+			require.NoError(t, s.Delete(id))
+			assert.ErrorIs(t, s.Delete(id), ErrTaskNotFound)
+			_, err := s.Get(id)
+			assert.ErrorIs(t, err, ErrTaskNotFound)
+			assert.ErrorIs(t, s.Done(id), ErrTaskNotFound)
+			assert.ErrorIs(t, s.Restore(keep), ErrTaskNotFound)
+
+			assert.Equal(t, []string{"Оставить"}, listTitles(t)(s.List(TaskFilter{})))
+			tags, err := s.Tags()
+			require.NoError(t, err)
+			assert.Empty(t, tags)
+
+			trash, err := s.Trash()
+			require.NoError(t, err)
+			require.Len(t, trash, 1)
+			assert.Equal(t, id, trash[0].ID)
+			assert.Equal(t, "2024-01-26T15:00:00Z", trash[0].DeletedAt)
+
+			require.NoError(t, s.Restore(id))
+			task, err := s.Get(id)
+			require.NoError(t, err)
+			assert.Empty(t, task.DeletedAt)
+			assert.Equal(t, []string{"trash"}, task.Tags)
+
+			// Only tasks deleted longer than the retention ago are purged
+			require.NoError(t, s.Delete(id))
+			n, err := s.PurgeTrash(time.Hour)
+			require.NoError(t, err)
+			assert.Equal(t, 0, n)
+			s.now = func() time.Time { return testNow.Add(2 * time.Hour) }
+			n, err = s.PurgeTrash(time.Hour)
+			require.NoError(t, err)
+			assert.Equal(t, 1, n)
+			assert.ErrorIs(t, s.Restore(id), ErrTaskNotFound)
+		}},
+		{"overdue", nil, func(t *testing.T, s *TaskService, ids []string) {
+			// Stored directly, as if the dates had passed since the tasks were saved
+			_, err := s.store.Import([]Task{
+				{Title: "Вчера", Date: "20240125"},
+				{Title: "Сегодня", Date: "20240126"},
+				{Title: "Завтра", Date: "20240127"},
+			})
+			require.NoError(t, err)
+
+			titles := listTitles(t)
+			assert.Equal(t, []string{"Вчера"}, titles(s.Overdue()))
+			assert.Equal(t, []string{"Сегодня"}, titles(s.Today()))
+
+			tasks, err := s.List(TaskFilter{})
+			require.NoError(t, err)
+			for _, task := range tasks {
+				assert.Equal(t, task.Title == "Вчера", task.Overdue, task.Title)
+			}
+
+			// 15:00 UTC is already the next day ten hours east
+			s.SetLocation(time.FixedZone("UTC+10", 10*60*60))
+			assert.Equal(t, []string{"Вчера", "Сегодня"}, titles(s.Overdue()))
+			assert.Equal(t, []string{"Завтра"}, titles(s.Today()))
+		}},
+		{"time", []Task{
+			{Title: "Ужин", Date: "20240127", Time: "19:00"},
+			{Title: "Планёрка", Date: "20240127", Time: "9:30", Duration: 15, Repeat: "d 1"},
+			{Title: "Весь день", Date: "20240127"},
+			{Title: "Раньше", Date: "20240126", Time: "23:00"},
+		}, func(t *testing.T, s *TaskService, ids []string) {
+			tasks, err := s.List(TaskFilter{})
+			require.NoError(t, err)
+			var list []string
+			for _, task := range tasks {
+				list = append(list, task.Date+" "+task.Time+" "+task.Title)
+			}
+			assert.Equal(t, []string{
+				"20240126 23:00 Раньше",
+				"20240127  Весь день",
+				"20240127 09:30 Планёрка",
+				"20240127 19:00 Ужин",
+			}, list)
+			assert.Equal(t, []string{"Ужин", "Планёрка", "Весь день", "Раньше"},
+				listTitles(t)(s.List(TaskFilter{Desc: true})))
+
+			// Done moves the date and keeps the time
+			meeting := ids[1]
+			require.NoError(t, s.Done(meeting))
+			task, err := s.Get(meeting)
+			require.NoError(t, err)
+			assert.Equal(t, "20240128", task.Date)
+			assert.Equal(t, "09:30", task.Time)
+			assert.Equal(t, 15, task.Duration)
+		}},
+		{"repeat end", []Task{
+			{Title: "Три раза", Date: "20240126", Repeat: "d 7 count 3"},
+			{Title: "До февраля", Date: "20240126", Repeat: "w 5 until 20240205"},
+		}, func(t *testing.T, s *TaskService, ids []string) {
+			// count 3 allows two moves; the third done finishes the task
+			id := ids[0]
+			for _, want := range []string{"20240202", "20240209"} {
+				require.NoError(t, s.Done(id))
+				task, err := s.Get(id)
+				require.NoError(t, err)
+				assert.Equal(t, want, task.Date)
+			}
+			task, err := s.Get(id)
+			require.NoError(t, err)
+			assert.Equal(t, 2, task.DoneCount)
+
+			// Editing the task keeps the number of completed occurrences
+			task.Title = "Три раза, не больше"
+			task.DoneCount = 0
+			require.NoError(t, s.Update(task))
+			require.NoError(t, s.Done(id))
+			_, err = s.Get(id)
+			assert.ErrorIs(t, err, ErrTaskNotFound)
+
+			id = ids[1]
+			require.NoError(t, s.Done(id))
+			task, err = s.Get(id)
+			require.NoError(t, err)
+			assert.Equal(t, "20240202", task.Date)
+			require.NoError(t, s.Done(id))
+			_, err = s.Get(id)
+			assert.ErrorIs(t, err, ErrTaskNotFound)
+		}},
+	}
+	for _, v := range tbl {
+		for name, store := range stores(t) {
+			t.Run(v.name+"/"+name, func(t *testing.T) {
+				s := newTestService(store, testNow)
+				var ids []string
+				if len(v.tasks) > 0 {
+					var err error
+					ids, err = s.Import(v.tasks)
+					require.NoError(t, err)
+				}
+				v.test(t, s, ids)
+			})
+		}
+	}
+}
+
+func TestServiceTimeZone(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	require.NoError(t, err)
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	tbl := []struct {
+		name  string
+		now   time.Time
+		loc   *time.Location
+		today string
+	}{
+		// 00:30 in Moscow is still the previous day in UTC
+		{"after midnight east of UTC", time.Date(2024, 1, 25, 21, 30, 0, 0, time.UTC), moscow, "20240126"},
+		// 23:30 in New York is already the next day in UTC
+		{"before midnight west of UTC", time.Date(2024, 1, 27, 4, 30, 0, 0, time.UTC), newYork, "20240126"},
+		// The day clocks go forward is 23 hours long
+		{"spring forward", time.Date(2024, 3, 11, 3, 30, 0, 0, time.UTC), newYork, "20240310"},
+		{"after spring forward", time.Date(2024, 3, 11, 4, 30, 0, 0, time.UTC), newYork, "20240311"},
+		// The day clocks go back is 25 hours long
+		{"fall back", time.Date(2024, 11, 4, 4, 30, 0, 0, time.UTC), newYork, "20241103"},
+		{"after fall back", time.Date(2024, 11, 4, 5, 30, 0, 0, time.UTC), newYork, "20241104"},
+	}
+	for _, v := range tbl {
+		t.Run(v.name, func(t *testing.T) {
+			s := newTestService(NewMemoryStore(), v.now)
+			s.SetLocation(v.loc)
+			today, err := time.Parse(dateFormat, v.today)
+			require.NoError(t, err)
+			yesterday := today.AddDate(0, 0, -1).Format(dateFormat)
+			tomorrow := today.AddDate(0, 0, 1).Format(dateFormat)
+
+			// Empty and past dates become today in the service's time zone
+			id, err := s.Create(Task{Title: "Без даты"})
+			require.NoError(t, err)
+			task, err := s.Get(id)
+			require.NoError(t, err)
+			assert.Equal(t, v.today, task.Date)
+			id, err = s.Create(Task{Title: "Вчера", Date: yesterday})
+			require.NoError(t, err)
+			task, err = s.Get(id)
+			require.NoError(t, err)
+			assert.Equal(t, v.today, task.Date)
+
+			// A daily task done today moves to tomorrow, not to today
+			id, err = s.Create(Task{Title: "Каждый день", Date: v.today, Repeat: "d 1"})
+			require.NoError(t, err)
+			require.NoError(t, s.Done(id))
+			task, err = s.Get(id)
+			require.NoError(t, err)
+			assert.Equal(t, tomorrow, task.Date)
+
+			next, err := NextDate(v.now.In(v.loc), yesterday, "d 1")
+			require.NoError(t, err)
+			assert.Equal(t, tomorrow, next)
+
+			tasks, err := s.Today()
+			require.NoError(t, err)
+			assert.Len(t, tasks, 2)
+		})
+	}
+}
+
+func TestNextDate(t *testing.T) {
+	tbl := []struct {
+		date, repeat string
+		want         string
+	}{
+		{"20240113", "d 7", "20240127"},
+		{"20240126", "w 1", "20240129"},
+		{"20240126", "m -1", "20240131"},
+		{"20240229", "y", "20250301"},
+		{"20240113", "d 7 until 20240127", "20240127"},
+		{"20240113", "d 7 until 20240126", ""},
+		{"20240113", "d 7 count 1", "20240127"},
+		{"20240126", "m -1 until 20241231", "20240131"},
+	}
+	for _, v := range tbl {
+		next, err := NextDate(testNow, v.date, v.repeat)
+		assert.NoError(t, err, "%v", v)
+		assert.Equal(t, v.want, next, "%v", v)
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	tbl := []struct {
+		date, repeat string
+		n            int
+		want         []string
+	}{
+		{"20240126", "d 7", 3, []string{"20240126", "20240202", "20240209"}},
+		// A past start moves to the next date, as when the task is saved
+		{"20240113", "d 7", 2, []string{"20240127", "20240203"}},
+		{"20240126", "w 1,5", 4, []string{"20240126", "20240129", "20240202", "20240205"}},
+		{"20240131", "m -1", 3, []string{"20240131", "20240229", "20240331"}},
+		{"20240229", "y", 2, []string{"20240229", "20250301"}},
+		{"20240126", "d 7 count 2", 10, []string{"20240126", "20240202"}},
+		{"20240126", "d 7 until 20240209", 10, []string{"20240126", "20240202", "20240209"}},
+		{"20240101", "", 5, []string{"20240126"}},
+		{"20240130", "", 5, []string{"20240130"}},
+	}
+	for _, v := range tbl {
+		dates, err := Occurrences(testNow, v.date, v.repeat, v.n)
+		assert.NoError(t, err, "%v", v)
+		assert.Equal(t, v.want, dates, "%v", v)
+	}
+
+	for _, repeat := range []string{"d 0", "x 1", "d 7 count 0"} {
+		_, err := Occurrences(testNow, "20240126", repeat, 3)
+		assert.Error(t, err, repeat)
+	}
+	_, err := Occurrences(testNow, "26.01.2024", "d 1", 3)
+	assert.Error(t, err)
+}
+
+func TestRRuleToRepeat(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	require.NoError(t, err)
+
+	tbl := []struct {
+		rrule string
+		loc   *time.Location
+		want  string
+	}{
+		{"FREQ=DAILY;INTERVAL=3", time.UTC, "d 3"},
+		{"FREQ=WEEKLY;BYDAY=MO,WE,FR;COUNT=5", time.UTC, "w 1,3,5 count 5"},
+		{"FREQ=YEARLY;UNTIL=20270101", moscow, "y until 20270101"},
+		{"FREQ=DAILY;UNTIL=20240301T235959Z", time.UTC, "d 1 until 20240301"},
+		// 23:59:59 UTC is already the next day in Moscow
+		{"FREQ=DAILY;UNTIL=20240301T235959Z", moscow, "d 1 until 20240302"},
+		{"FREQ=DAILY;UNTIL=20240301T235959", moscow, "d 1 until 20240301"},
+	}
+	for _, v := range tbl {
+		repeat, err := rruleToRepeat(v.rrule, v.loc)
+		assert.NoError(t, err, "%v", v)
+		assert.Equal(t, v.want, repeat, "%v", v)
+	}
+
+	for _, rrule := range []string{"FREQ=DAILY;UNTIL=2024", "FREQ=DAILY;UNTIL=20240301;COUNT=2", "FREQ=HOURLY"} {
+		_, err := rruleToRepeat(rrule, time.UTC)
+		assert.Error(t, err, rrule)
+	}
+}
+
+func TestParseRepeat(t *testing.T) {
+	for repeat, want := range map[string]RepeatRule{
+		"y":                  {Kind: RepeatYearly},
+		"d 7":                {Kind: RepeatDaily, Interval: 7},
+		"w 5,1":              {Kind: RepeatWeekly, Weekdays: []int{5, 1}},
+		"m -1,15":            {Kind: RepeatMonthly, MonthDays: []int{-1, 15}},
+		"m 1 1,7":            {Kind: RepeatMonthly, MonthDays: []int{1}, Months: []int{1, 7}},
+		"d 7 until 20271231": {Kind: RepeatDaily, Interval: 7, End: RepeatEnd{Until: "20271231"}},
+		"w 6,7 count 10":     {Kind: RepeatWeekly, Weekdays: []int{6, 7}, End: RepeatEnd{Count: 10}},
+	} {
+		rule, err := ParseRepeat(repeat)
+		require.NoError(t, err, repeat)
+		assert.Equal(t, want, rule, repeat)
+		assert.Equal(t, repeat, rule.String())
+	}
+
+	// Rules are stored in their canonical form
+	for repeat, want := range map[string]string{
+		" m  07,19 05,6 ": "m 7,19 5,6",
+		"w 1, 2":          "w 1,2",
+		"m 1, 15":         "m 1,15",
+		"m 1 ,15 1, 7":    "m 1,15 1,7",
+	} {
+		rule, err := ParseRepeat(repeat)
+		require.NoError(t, err, repeat)
+		assert.Equal(t, want, rule.String(), repeat)
+	}
+
+	for repeat, code := range map[string]ErrorCode{
+		"":                  CodeMissingArgument,
+		"k 34":              CodeUnknownRule,
+		"dd 5":              CodeUnknownRule,
+		"d":                 CodeMissingArgument,
+		"d 401":             CodeBadInterval,
+		"d x":               CodeBadInterval,
+		"d 5 6":             CodeBadFormat,
+		"y 2":               CodeBadFormat,
+		"w 8,4,5":           CodeDayOutOfRange,
+		"w":                 CodeMissingArgument,
+		"m 40,11,19":        CodeDayOutOfRange,
+		"m -2,-3":           CodeDayOutOfRange,
+		"m 0":               CodeDayOutOfRange,
+		"m 1 13":            CodeMonthOutOfRange,
+		"m 1 2 3":           CodeBadFormat,
+		"d 7 until 2027":    CodeBadEnd,
+		"d 7 count 0":       CodeBadEnd,
+		"d 7 count several": CodeBadEnd,
+		"w 1,,2":            CodeBadFormat,
+		"w 1,":              CodeBadFormat,
+		"m 1 1,,2":          CodeBadFormat,
+	} {
+		_, err := ParseRepeat(repeat)
+		var repeatErr *RepeatError
+		if assert.True(t, errors.As(err, &repeatErr), repeat) {
+			assert.Equal(t, code, repeatErr.Code, repeat)
+		}
+	}
+
+	_, err := NextDate(testNow, "20240126", "m 31 2")
+	var repeatErr *RepeatError
+	require.True(t, errors.As(err, &repeatErr))
+	assert.Equal(t, CodeNoMatch, repeatErr.Code)
+
+	// Service errors keep the code of the rule
+	s := newTestService(NewMemoryStore(), testNow)
+	_, err = s.Create(Task{Title: "Задача", Repeat: "w 0"})
+	assert.Equal(t, CodeDayOutOfRange, errorCode(err))
+	_, err = s.Import([]Task{{Title: "Задача", Date: "20240101", Repeat: "d 1 until 20240110"}})
+	assert.Equal(t, CodeRuleEnded, errorCode(err))
+}
+
+func TestDescribeRepeat(t *testing.T) {
+	for repeat, want := range map[string][2]string{
+		"y":                  {"ежегодно", "yearly"},
+		"d 1":                {"ежедневно", "daily"},
+		"d 2":                {"каждые 2 дня", "every 2 days"},
+		"d 14":               {"каждые 14 дней", "every 14 days"},
+		"d 21":               {"каждый 21 день", "every 21 days"},
+		"w 1,3,5":            {"по понедельникам, средам и пятницам", "every Monday, Wednesday and Friday"},
+		"w 7":                {"по воскресеньям", "every Sunday"},
+		"m 1,15":             {"1 и 15 числа каждого месяца", "on the 1st and 15th of every month"},
+		"m -1":               {"последнего числа каждого месяца", "on the last day of every month"},
+		"m 22,-2 1,7":        {"22 и предпоследнего числа в январе и июле", "on the 22nd and second to last day of January and July"},
+		"d 7 until 20240301": {"каждые 7 дней до 01.03.2024", "every 7 days until March 1, 2024"},
+		"w 2 count 1":        {"по вторникам, 1 раз", "every Tuesday, once"},
+		"y count 3":          {"ежегодно, 3 раза", "yearly, 3 times"},
+		"m 11 count 12":      {"11 числа каждого месяца, 12 раз", "on the 11th of every month, 12 times"},
+	} {
+		rule, err := ParseRepeat(repeat)
+		require.NoError(t, err, repeat)
+		assert.Equal(t, want[0], DescribeRepeat(rule, "ru"), repeat)
+		assert.Equal(t, want[1], DescribeRepeat(rule, "en"), repeat)
+	}
+
+	for header, want := range map[string]string{
+		"":                        "ru",
+		"en":                      "en",
+		"en-US,en;q=0.9,ru;q=0.8": "en",
+		"ru-RU,ru;q=0.9,en;q=0.8": "ru",
+		"de-DE,en;q=0.5,ru;q=0.7": "ru",
+		"fr-FR,de;q=0.9":          "ru",
+		"de,EN-GB;q=0.3":          "en",
+		"ru;q=0,en;q=0.1":         "en",
+		"ru;q=0":                  "en",
+		"en;q=0":                  "ru",
+		"en;q=0,ru;q=0":           "ru",
+	} {
+		assert.Equal(t, want, preferredLanguage(header), header)
+	}
+}
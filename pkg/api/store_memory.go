@@ -0,0 +1,327 @@
+package api
+
+import (
+	"maps"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps tasks in memory; it is meant for tests and for running
+// the service without a database file
+type MemoryStore struct {
+	mu          sync.Mutex
+	tasks       map[int64]Task
+	completions map[int64][]Completion // oldest first
+	lastID      int64
+}
+
+// NewMemoryStore returns an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{tasks: make(map[int64]Task), completions: make(map[int64][]Completion)}
+}
+
+// copyTask detaches the tags of a task from the stored slice
+func copyTask(task Task) Task {
+	task.Tags = slices.Clone(task.Tags)
+	return task
+}
+
+// parseID converts a task id; ids that aren't numbers can't match any task
+func parseID(id string) (int64, bool) {
+	n, err := strconv.ParseInt(id, 10, 64)
+	return n, err == nil
+}
+
+// active returns a task that is neither in the trash nor finished
+func (s *MemoryStore) active(id string) (Task, bool) {
+	n, ok := parseID(id)
+	task, found := s.tasks[n]
+	return task, ok && found && task.DeletedAt == "" && task.FinishedAt == ""
+}
+
+// Create implements TaskStore
+func (s *MemoryStore) Create(task Task) (string, error) {
+	ids, err := s.Import([]Task{task})
+	if err != nil {
+		return "", err
+	}
+	return ids[0], nil
+}
+
+// Import implements TaskStore
+func (s *MemoryStore) Import(tasks []Task) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		s.lastID++
+		task.ID = strconv.FormatInt(s.lastID, 10)
+		if task.Tags != nil {
+			task.Tags = slices.Sorted(slices.Values(task.Tags))
+		}
+		s.tasks[s.lastID] = copyTask(task)
+		ids = append(ids, task.ID)
+	}
+	return ids, nil
+}
+
+// Get implements TaskStore
+func (s *MemoryStore) Get(id string) (Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, found := s.active(id)
+	if !found {
+		return Task{}, ErrTaskNotFound
+	}
+	return copyTask(task), nil
+}
+
+// Update implements TaskStore
+func (s *MemoryStore) Update(task Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, found := s.active(task.ID)
+	if !found {
+		return ErrTaskNotFound
+	}
+	if task.Tags == nil {
+		task.Tags = old.Tags
+	} else {
+		task.Tags = slices.Sorted(slices.Values(task.Tags))
+	}
+	task.ID = old.ID
+	task.DoneCount = old.DoneCount
+	task.DeletedAt = ""
+	n, _ := parseID(old.ID)
+	s.tasks[n] = copyTask(task)
+	return nil
+}
+
+// Delete implements TaskStore
+func (s *MemoryStore) Delete(id string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, found := s.active(id)
+	if !found {
+		return ErrTaskNotFound
+	}
+	task.DeletedAt = at.UTC().Format(time.RFC3339)
+	n, _ := parseID(id)
+	s.tasks[n] = task
+	return nil
+}
+
+// Restore implements TaskStore
+func (s *MemoryStore) Restore(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, ok := parseID(id)
+	task, found := s.tasks[n]
+	if !ok || !found || task.DeletedAt == "" {
+		return ErrTaskNotFound
+	}
+	task.DeletedAt = ""
+	s.tasks[n] = task
+	return nil
+}
+
+// Purge implements TaskStore
+func (s *MemoryStore) Purge(before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// RFC 3339 times in UTC sort as strings
+	cutoff := before.UTC().Format(time.RFC3339)
+	purged := 0
+	for n, task := range s.tasks {
+		if task.DeletedAt != "" && task.DeletedAt < cutoff {
+			delete(s.tasks, n)
+			delete(s.completions, n)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// Done implements TaskStore
+func (s *MemoryStore) Done(id string, at time.Time, next func(Task) (string, error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, found := s.active(id)
+	if !found {
+		return ErrTaskNotFound
+	}
+
+	date, err := next(copyTask(task))
+	if err != nil {
+		return err
+	}
+	n, _ := parseID(id)
+	if date == "" && task.Repeat == "" {
+		delete(s.tasks, n)
+		delete(s.completions, n)
+		return nil
+	}
+	completedAt := at.UTC().Format(time.RFC3339)
+	s.completions[n] = append(s.completions[n], Completion{Date: task.Date, CompletedAt: completedAt})
+	if date == "" {
+		// The rule has ended: the task keeps its date and history but leaves the schedule
+		task.FinishedAt = completedAt
+	} else {
+		task.Date = date
+	}
+	task.DoneCount++
+	s.tasks[n] = task
+	return nil
+}
+
+// History implements TaskStore; finished tasks have a history too
+func (s *MemoryStore) History(id string) ([]Completion, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n, ok := parseID(id)
+	task, found := s.tasks[n]
+	if !ok || !found || task.DeletedAt != "" {
+		return nil, ErrTaskNotFound
+	}
+	completions := slices.Clone(s.completions[n])
+	slices.Reverse(completions)
+	return completions, nil
+}
+
+// compareValues orders two sort key values the way SQLite does for the same column
+func compareValues(a, b any) int {
+	if as, ok := a.(string); ok {
+		bs, _ := b.(string)
+		return strings.Compare(as, bs)
+	}
+	return cmpNumbers(toFloat(a), toFloat(b))
+}
+
+func cmpNumbers(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// toFloat converts numeric sort key values, which come back from cursors as float64
+func toFloat(v any) float64 {
+	switch n := v.(type) {
+	case int:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case float64:
+		return n
+	}
+	return 0
+}
+
+// compareTasks orders tasks by the given keys, returning a negative number when a comes first
+func compareTasks(keys []sortKey, a, b []any) int {
+	for i, k := range keys {
+		c := compareValues(a[i], b[i])
+		if k.desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// matches reports whether a task passes the non-paging conditions of the filter
+func (f TaskFilter) matches(task Task) bool {
+	if task.FinishedAt != "" || f.Deleted != (task.DeletedAt != "") {
+		return false
+	}
+	if f.Date != "" && task.Date != f.Date {
+		return false
+	}
+	if f.Before != "" && task.Date >= f.Before {
+		return false
+	}
+	if f.Search != "" {
+		search := strings.ToLower(f.Search)
+		if !strings.Contains(strings.ToLower(task.Title), search) &&
+			!strings.Contains(strings.ToLower(task.Comment), search) {
+			return false
+		}
+	}
+	for _, tag := range f.Tags {
+		if !slices.Contains(task.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// List implements TaskStore
+func (s *MemoryStore) List(filter TaskFilter) ([]Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := sortKeys(filter.Sort, filter.Desc)
+	values := func(task Task) []any {
+		v := make([]any, len(keys))
+		for i, k := range keys {
+			v[i] = k.value(task)
+		}
+		return v
+	}
+
+	var tasks []Task
+	for _, task := range s.tasks {
+		if !filter.matches(task) {
+			continue
+		}
+		if len(filter.After) > 0 && compareTasks(keys, values(task), filter.After) <= 0 {
+			continue
+		}
+		tasks = append(tasks, copyTask(task))
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return compareTasks(keys, values(tasks[i]), values(tasks[j])) < 0
+	})
+	if filter.Limit > 0 && len(tasks) > filter.Limit {
+		tasks = tasks[:filter.Limit]
+	}
+	return tasks, nil
+}
+
+// Tags implements TaskStore
+func (s *MemoryStore) Tags() ([]TagCount, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make(map[string]int)
+	for _, task := range s.tasks {
+		if task.DeletedAt != "" || task.FinishedAt != "" {
+			continue
+		}
+		for _, tag := range task.Tags {
+			counts[tag]++
+		}
+	}
+	tags := make([]TagCount, 0, len(counts))
+	for _, name := range slices.Sorted(maps.Keys(counts)) {
+		tags = append(tags, TagCount{Name: name, Count: counts[name]})
+	}
+	return tags, nil
+}
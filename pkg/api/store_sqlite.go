@@ -0,0 +1,363 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// taskColumns selects a full Task, replacing NULL with empty strings
+const taskColumns = `id, date, title, COALESCE(comment, '') AS comment, COALESCE(repeat, '') AS repeat, priority,
+	time, duration, done_count, COALESCE(deleted_at, '') AS deleted_at, COALESCE(finished_at, '') AS finished_at`
+
+// activeTask selects tasks that are neither in the trash nor finished
+const activeTask = `deleted_at IS NULL AND finished_at IS NULL`
+
+// SQLiteStore keeps tasks in the scheduler table of a SQLite database
+type SQLiteStore struct {
+	db *sqlx.DB
+}
+
+// NewSQLiteStore returns a store backed by an open database with an up-to-date schema
+func NewSQLiteStore(db *sqlx.DB) *SQLiteStore {
+	return &SQLiteStore{db: db}
+}
+
+// insertTask saves a task with its tags and returns the new id
+func insertTask(tx *sqlx.Tx, task Task) (string, error) {
+	result, err := tx.NamedExec(
+		`INSERT INTO scheduler (date, title, comment, repeat, priority, time, duration, done_count)
+		VALUES (:date, :title, :comment, :repeat, :priority, :time, :duration, :done_count)`,
+		task,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(id, 10), setTaskTags(tx, id, task.Tags)
+}
+
+// setTaskTags replaces the tags of a task, creating missing tags
+func setTaskTags(tx *sqlx.Tx, taskID any, tags []string) error {
+	if _, err := tx.Exec(`DELETE FROM task_tags WHERE task_id = ?`, taskID); err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO tags (name) VALUES (?)`, tag); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`INSERT INTO task_tags (task_id, tag_id) SELECT ?, id FROM tags WHERE name = ?`, taskID, tag)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadTags fills in the tags of the given tasks
+func loadTags(q sqlx.Queryer, tasks []Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	ids := make([]string, len(tasks))
+	for i, task := range tasks {
+		ids[i] = task.ID
+	}
+
+	query, args, err := sqlx.In(`SELECT tt.task_id, t.name FROM task_tags tt JOIN tags t ON t.id = tt.tag_id
+		WHERE tt.task_id IN (?) ORDER BY t.name`, ids)
+	if err != nil {
+		return err
+	}
+	var rows []struct {
+		TaskID string `db:"task_id"`
+		Name   string `db:"name"`
+	}
+	if err := sqlx.Select(q, &rows, query, args...); err != nil {
+		return err
+	}
+
+	byTask := make(map[string][]string)
+	for _, row := range rows {
+		byTask[row.TaskID] = append(byTask[row.TaskID], row.Name)
+	}
+	for i := range tasks {
+		tasks[i].Tags = byTask[tasks[i].ID]
+	}
+	return nil
+}
+
+// Create implements TaskStore
+func (s *SQLiteStore) Create(task Task) (string, error) {
+	ids, err := s.Import([]Task{task})
+	if err != nil {
+		return "", err
+	}
+	return ids[0], nil
+}
+
+// Import implements TaskStore
+func (s *SQLiteStore) Import(tasks []Task) ([]string, error) {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	ids := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		id, err := insertTask(tx, task)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, tx.Commit()
+}
+
+// Get implements TaskStore
+func (s *SQLiteStore) Get(id string) (Task, error) {
+	var task Task
+	err := s.db.Get(&task, `SELECT `+taskColumns+` FROM scheduler WHERE id = ? AND `+activeTask, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return task, ErrTaskNotFound
+	}
+	if err != nil {
+		return task, err
+	}
+
+	tasks := []Task{task}
+	err = loadTags(s.db, tasks)
+	return tasks[0], err
+}
+
+// Update implements TaskStore
+func (s *SQLiteStore) Update(task Task) error {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.NamedExec(
+		`UPDATE scheduler SET date=:date, title=:title, comment=:comment, repeat=:repeat, priority=:priority,
+		time=:time, duration=:duration
+		WHERE id=:id AND `+activeTask,
+		task,
+	)
+
+	if err := checkAffected(result, err); err != nil {
+		return err
+	}
+
+	if task.Tags != nil {
+		if err := setTaskTags(tx, task.ID, task.Tags); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Delete implements TaskStore
+func (s *SQLiteStore) Delete(id string, at time.Time) error {
+	result, err := s.db.Exec(`UPDATE scheduler SET deleted_at = ? WHERE id = ? AND `+activeTask,
+		at.UTC().Format(time.RFC3339), id)
+	return checkAffected(result, err)
+}
+
+// Restore implements TaskStore
+func (s *SQLiteStore) Restore(id string) error {
+	result, err := s.db.Exec(`UPDATE scheduler SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL`, id)
+	return checkAffected(result, err)
+}
+
+// Purge implements TaskStore
+func (s *SQLiteStore) Purge(before time.Time) (int, error) {
+	result, err := s.db.Exec(`DELETE FROM scheduler WHERE deleted_at < ?`, before.UTC().Format(time.RFC3339))
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
+
+// checkAffected turns a statement that changed no rows into ErrTaskNotFound
+func checkAffected(result sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrTaskNotFound
+	}
+	return nil
+}
+
+// Done implements TaskStore; the task is read and changed in one transaction
+// so concurrent calls can't advance it twice
+func (s *SQLiteStore) Done(id string, at time.Time, next func(Task) (string, error)) error {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var task Task
+	err = tx.Get(&task, `SELECT `+taskColumns+` FROM scheduler WHERE id = ? AND `+activeTask, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrTaskNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	date, err := next(task)
+	if err != nil {
+		return err
+	}
+	if date == "" && task.Repeat == "" {
+		if _, err = tx.Exec(`DELETE FROM scheduler WHERE id = ?`, task.ID); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+	completedAt := at.UTC().Format(time.RFC3339)
+	_, err = tx.Exec(`INSERT INTO completions (task_id, date, completed_at) VALUES (?, ?, ?)`,
+		task.ID, task.Date, completedAt)
+	if err != nil {
+		return err
+	}
+	if date == "" {
+		// The rule has ended: the task keeps its date and history but leaves the schedule
+		_, err = tx.Exec(`UPDATE scheduler SET done_count = done_count + 1, finished_at = ? WHERE id = ?`,
+			completedAt, task.ID)
+	} else {
+		_, err = tx.Exec(`UPDATE scheduler SET date = ?, done_count = done_count + 1 WHERE id = ?`, date, task.ID)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// afterCursor builds the condition selecting rows that come strictly after the cursor values
+func afterCursor(keys []sortKey, values []any) (string, []any) {
+	var conds []string
+	var args []any
+	for i, k := range keys {
+		var parts []string
+		for j := 0; j < i; j++ {
+			parts = append(parts, keys[j].expr+` = ?`)
+			args = append(args, values[j])
+		}
+		op := ` > ?`
+		if k.desc {
+			op = ` < ?`
+		}
+		parts = append(parts, k.expr+op)
+		args = append(args, values[i])
+		conds = append(conds, `(`+strings.Join(parts, ` AND `)+`)`)
+	}
+	return `(` + strings.Join(conds, ` OR `) + `)`, args
+}
+
+// History implements TaskStore; finished tasks have a history too
+func (s *SQLiteStore) History(id string) ([]Completion, error) {
+	var exists bool
+	err := s.db.Get(&exists, `SELECT EXISTS (SELECT 1 FROM scheduler WHERE id = ? AND deleted_at IS NULL)`, id)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrTaskNotFound
+	}
+
+	var completions []Completion
+	err = s.db.Select(&completions,
+		`SELECT date, completed_at FROM completions WHERE task_id = ? ORDER BY completed_at DESC, id DESC`, id)
+	return completions, err
+}
+
+// List implements TaskStore
+func (s *SQLiteStore) List(filter TaskFilter) ([]Task, error) {
+	keys := sortKeys(filter.Sort, filter.Desc)
+
+	// Tasks in the trash are only listed on request, finished tasks never
+	where := []string{activeTask}
+	if filter.Deleted {
+		where = []string{`deleted_at IS NOT NULL`, `finished_at IS NULL`}
+	}
+	var args []any
+	if filter.Date != "" {
+		where = append(where, `date = ?`)
+		args = append(args, filter.Date)
+	}
+	if filter.Before != "" {
+		where = append(where, `date < ?`)
+		args = append(args, filter.Before)
+	}
+	if filter.Search != "" {
+		// instr avoids treating % and _ in the search text as LIKE wildcards
+		where = append(where, `(instr(unicode_lower(title), ?) > 0 OR instr(unicode_lower(COALESCE(comment, '')), ?) > 0)`)
+		search := strings.ToLower(filter.Search)
+		args = append(args, search, search)
+	}
+	if len(filter.Tags) > 0 {
+		// Keep tasks that have all of the tags
+		cond, condArgs, err := sqlx.In(`id IN (SELECT tt.task_id FROM task_tags tt JOIN tags t ON t.id = tt.tag_id
+			WHERE t.name IN (?) GROUP BY tt.task_id HAVING COUNT(*) = ?)`, filter.Tags, len(filter.Tags))
+		if err != nil {
+			return nil, err
+		}
+		where = append(where, cond)
+		args = append(args, condArgs...)
+	}
+	if len(filter.After) > 0 {
+		// Continue strictly after the last task of the previous page
+		cond, condArgs := afterCursor(keys, filter.After)
+		where = append(where, cond)
+		args = append(args, condArgs...)
+	}
+
+	query := `SELECT ` + taskColumns + ` FROM scheduler WHERE ` + strings.Join(where, ` AND `)
+	var order []string
+	for _, k := range keys {
+		if k.desc {
+			order = append(order, k.expr+` DESC`)
+		} else {
+			order = append(order, k.expr)
+		}
+	}
+	query += ` ORDER BY ` + strings.Join(order, `, `)
+	if filter.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, filter.Limit)
+	}
+
+	var tasks []Task
+	if err := s.db.Select(&tasks, query, args...); err != nil {
+		return nil, err
+	}
+	return tasks, loadTags(s.db, tasks)
+}
+
+// Tags implements TaskStore
+func (s *SQLiteStore) Tags() ([]TagCount, error) {
+	var tags []TagCount
+	err := s.db.Select(&tags, `SELECT t.name, COUNT(*) AS count FROM tags t
+		JOIN task_tags tt ON tt.tag_id = t.id
+		JOIN scheduler s ON s.id = tt.task_id AND s.deleted_at IS NULL AND s.finished_at IS NULL
+		GROUP BY t.id ORDER BY t.name`)
+	return tags, err
+}
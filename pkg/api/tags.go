@@ -0,0 +1,59 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// maxTagLength limits the length of a single tag name
+const maxTagLength = 64
+
+// TagCount is one entry of the GET /api/tags response
+type TagCount struct {
+	Name  string `json:"name" db:"name"`
+	Count int    `json:"count" db:"count"`
+}
+
+// normalizeTags trims and lowercases tag names and drops duplicates,
+// so "Work" and "work " end up as the same tag
+func normalizeTags(tags []string) ([]string, error) {
+	if tags == nil {
+		return nil, nil
+	}
+	seen := make(map[string]bool)
+	result := []string{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			return nil, fmt.Errorf("tag can't be empty")
+		}
+		if len([]rune(tag)) > maxTagLength {
+			return nil, fmt.Errorf("tag is too long: must be at most %d characters", maxTagLength)
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			result = append(result, tag)
+		}
+	}
+	return result, nil
+}
+
+// tagsHandler handles GET /api/tags to list tags with the number of tasks using them
+func tagsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tags, err := service.Tags()
+	if err != nil {
+		writeServiceError(w, err, "failed to fetch tags")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string][]TagCount{"tags": tags})
+}
@@ -2,18 +2,40 @@ package api
 
 import (
 	"encoding/json"
-	"fmt"
-	"go_final_project/pkg/db"
 	"net/http"
-	"time"
 )
 
+// Task priorities range from maxPriority (most urgent) down to 0 (none)
+const maxPriority = 3
+
+// maxDuration limits the duration of a timed task, in minutes
+const maxDuration = 24 * 60
+
 type Task struct {
 	ID      string `json:"id" db:"id"`
 	Date    string `json:"date" db:"date"`
 	Title   string `json:"title" db:"title"`
 	Comment string `json:"comment" db:"comment"`
 	Repeat  string `json:"repeat" db:"repeat"`
+	// Time is the optional time of day, HH:MM; tasks without it take the whole day
+	Time string `json:"time,omitempty" db:"time"`
+	// Duration is the length of a timed task in minutes, 0 if unknown
+	Duration int `json:"duration,omitempty" db:"duration"`
+	// Priority 0 means no priority and is omitted from responses
+	Priority int `json:"priority,omitempty" db:"priority"`
+	// Tags are stored in the tags table; on update a missing list keeps the current tags
+	Tags []string `json:"tags,omitempty" db:"-"`
+	// DoneCount is the number of completed occurrences of a repeating task
+	DoneCount int `json:"done_count,omitempty" db:"done_count"`
+	// DeletedAt is the RFC 3339 time the task was moved to the trash, empty for active tasks
+	DeletedAt string `json:"deleted_at,omitempty" db:"deleted_at"`
+	// FinishedAt is the RFC 3339 time the repeat rule of the task ended, empty while it is scheduled
+	FinishedAt string `json:"-" db:"finished_at"`
+	// Overdue is set by TaskService.List for tasks dated before today; only list
+	// responses show it, through ListedTask
+	Overdue bool `json:"-" db:"-"`
+	// RepeatText describes Repeat for people in list responses, e.g. "каждые 14 дней"
+	RepeatText string `json:"repeat_text,omitempty" db:"-"`
 }
 
 type DeleteRequest struct {
@@ -22,67 +44,35 @@ type DeleteRequest struct {
 
 func taskHandler(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
-	case http.MethodPost:
-		// Create a new task
-		var task Task
-		if err := json.NewDecoder(r.Body).Decode(&task); err != nil {
-			writeError(w, "invalid JSON", http.StatusBadRequest)
-			return
-		}
-
-		if task.Title == "" {
-			writeError(w, "title is required", http.StatusBadRequest)
-			return
-		}
-
-		// Set date to today if empty
-		now := time.Now()
-		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
-		if task.Date == "" {
-			task.Date = today.Format(dateFormat)
-		}
-
-		// Validate date format
-		date, err := time.Parse(dateFormat, task.Date)
+	case http.MethodGet:
+		// Fetch a single task by id
+		task, err := service.Get(r.FormValue("id"))
 		if err != nil {
-			writeError(w, "invalid date format", http.StatusBadRequest)
+			writeServiceError(w, err, "failed to fetch task")
 			return
 		}
 
-		// If date is before today, set it to today
-		if date.Before(today) {
-			task.Date = today.Format(dateFormat)
-		}
-
-		// Calculate next date if repeat is set and date is not today with repeat="d 1"
-		if task.Repeat != "" && !(task.Repeat == "d 1" && task.Date == today.Format(dateFormat)) {
-			next, err := NextDate(now, task.Date, task.Repeat)
-			if err != nil {
-				writeError(w, err.Error(), http.StatusBadRequest)
-				return
-			}
-			task.Date = next
-		}
+		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(task)
 
-		// Insert task into database
-		result, err := db.DB.NamedExec(
-			`INSERT INTO scheduler (date, title, comment, repeat) VALUES (:date, :title, :comment, :repeat)`,
-			task,
-		)
-		if err != nil {
-			writeError(w, "failed to save task", http.StatusInternalServerError)
+	case http.MethodPost:
+		// Create a new task
+		var task Task
+		if err := json.NewDecoder(r.Body).Decode(&task); err != nil {
+			writeError(w, "invalid JSON", http.StatusBadRequest)
 			return
 		}
 
-		id, err := result.LastInsertId()
+		id, err := service.Create(task)
 		if err != nil {
-			writeError(w, "failed to get task ID", http.StatusInternalServerError)
+			writeServiceError(w, err, "failed to save task")
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(map[string]string{"id": fmt.Sprintf("%d", id)})
+		json.NewEncoder(w).Encode(map[string]string{"id": id})
 
 	case http.MethodPut:
 		// Update an existing task
@@ -92,63 +82,8 @@ func taskHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		if task.ID == "" {
-			writeError(w, "id is required", http.StatusBadRequest)
-			return
-		}
-
-		if task.Title == "" {
-			writeError(w, "title is required", http.StatusBadRequest)
-			return
-		}
-
-		// Set date to today if empty
-		now := time.Now()
-		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
-		if task.Date == "" {
-			task.Date = today.Format(dateFormat)
-		}
-
-		// Validate date format
-		date, err := time.Parse(dateFormat, task.Date)
-		if err != nil {
-			writeError(w, "invalid date format", http.StatusBadRequest)
-			return
-		}
-
-		// If date is before today, set it to today
-		if date.Before(today) {
-			task.Date = today.Format(dateFormat)
-		}
-
-		// Calculate next date if repeat is set and date is not today with repeat="d 1"
-		if task.Repeat != "" && !(task.Repeat == "d 1" && task.Date == today.Format(dateFormat)) {
-			next, err := NextDate(now, task.Date, task.Repeat)
-			if err != nil {
-				writeError(w, err.Error(), http.StatusBadRequest)
-				return
-			}
-			task.Date = next
-		}
-
-		// Update task in database
-		result, err := db.DB.NamedExec(
-			`UPDATE scheduler SET date=:date, title=:title, comment=:comment, repeat=:repeat WHERE id=:id`,
-			task,
-		)
-		if err != nil {
-			writeError(w, "failed to update task", http.StatusInternalServerError)
-			return
-		}
-
-		rowsAffected, err := result.RowsAffected()
-		if err != nil {
-			writeError(w, "failed to check update result", http.StatusInternalServerError)
-			return
-		}
-
-		if rowsAffected == 0 {
-			writeError(w, "task not found", http.StatusNotFound)
+		if err := service.Update(task); err != nil {
+			writeServiceError(w, err, "failed to update task")
 			return
 		}
 
@@ -157,33 +92,17 @@ func taskHandler(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(map[string]string{})
 
 	case http.MethodDelete:
-		// Delete a task
-		var req DeleteRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			writeError(w, "invalid JSON", http.StatusBadRequest)
-			return
-		}
-
-		if req.ID == "" {
-			writeError(w, "id is required", http.StatusBadRequest)
-			return
-		}
-
-		// Delete task from database
-		result, err := db.DB.Exec(`DELETE FROM scheduler WHERE id = ?`, req.ID)
-		if err != nil {
-			writeError(w, "failed to delete task", http.StatusInternalServerError)
-			return
-		}
-
-		rowsAffected, err := result.RowsAffected()
-		if err != nil {
-			writeError(w, "failed to check delete result", http.StatusInternalServerError)
-			return
+		// Delete a task; the id comes from the query string or, failing that, the JSON body
+		req := DeleteRequest{ID: r.URL.Query().Get("id")}
+		if req.ID == "" && r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeError(w, "invalid JSON", http.StatusBadRequest)
+				return
+			}
 		}
 
-		if rowsAffected == 0 {
-			writeError(w, "task not found", http.StatusNotFound)
+		if err := service.Delete(req.ID); err != nil {
+			writeServiceError(w, err, "failed to delete task")
 			return
 		}
 
@@ -1,33 +1,240 @@
 package api
 
 import (
+	"encoding/base64"
 	"encoding/json"
-	"go_final_project/pkg/db"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
-// tasksHandler handles GET /api/tasks to retrieve all tasks from the scheduler table
+// searchDateFormat is the date format users type into the search box
+const searchDateFormat = "02.01.2006"
+
+const (
+	defaultTasksLimit = 50  // page size when the client doesn't ask for one
+	maxTasksLimit     = 500 // upper bound on the page size a client can request
+)
+
+// TasksResponse is the body of GET /api/tasks; NextCursor is set when more tasks follow
+type TasksResponse struct {
+	Tasks      []ListedTask `json:"tasks"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
+
+// ListedTask is a task in list responses, which always tell whether it is overdue
+type ListedTask struct {
+	Task
+	Overdue bool `json:"overdue"`
+}
+
+// listedTasks prepares tasks for a list response in the language the client prefers
+func listedTasks(r *http.Request, tasks []Task) []ListedTask {
+	describeTasks(r, tasks)
+	listed := make([]ListedTask, len(tasks))
+	for i, task := range tasks {
+		listed[i] = ListedTask{Task: task, Overdue: task.Overdue}
+	}
+	return listed
+}
+
+// sortKey is one column of the ORDER BY clause; pages continue after the
+// values of these keys in the last task of the previous page
+type sortKey struct {
+	expr  string
+	desc  bool
+	value func(Task) any
+}
+
+// taskSorts maps the sort parameter to the key tasks are ordered by; desc is the
+// default direction, so priority sorts most urgent first unless an order is given
+var taskSorts = map[string]sortKey{
+	"date":     {`date`, false, func(t Task) any { return t.Date }},
+	"priority": {`priority`, true, func(t Task) any { return t.Priority }},
+	"title":    {`unicode_lower(title)`, false, func(t Task) any { return strings.ToLower(t.Title) }},
+}
+
+// sortKeys returns the ORDER BY keys for a sort: the sort column, then date, time and id
+// ascending so that tasks with equal values keep a stable order. Sorting by date orders
+// the time in the same direction; all-day tasks come before timed tasks of the same day
+func sortKeys(sort string, desc bool) []sortKey {
+	first := taskSorts[sort]
+	first.desc = desc
+	keys := []sortKey{first}
+	timeDesc := false
+	if sort == "date" {
+		timeDesc = desc
+	} else {
+		keys = append(keys, sortKey{expr: `date`, value: func(t Task) any { return t.Date }})
+	}
+	keys = append(keys, sortKey{expr: `time`, desc: timeDesc, value: func(t Task) any { return t.Time }})
+	return append(keys, sortKey{expr: `id`, value: func(t Task) any {
+		id, _ := strconv.ParseInt(t.ID, 10, 64)
+		return id
+	}})
+}
+
+// pageCursor is the decoded form of the opaque next_cursor value
+type pageCursor struct {
+	Sort   string `json:"s"`
+	Desc   bool   `json:"d,omitempty"`
+	Values []any  `json:"v"`
+}
+
+// encodeCursor builds an opaque cursor pointing just after the given task
+func encodeCursor(sort string, desc bool, keys []sortKey, task Task) string {
+	c := pageCursor{Sort: sort, Desc: desc}
+	for _, k := range keys {
+		c.Values = append(c.Values, k.value(task))
+	}
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeCursor extracts the key values of the last task of the previous page
+func decodeCursor(cursor, sort string, desc bool, keys []sortKey) ([]any, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	var c pageCursor
+	if err := json.Unmarshal(raw, &c); err != nil || len(c.Values) != len(keys) {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	if c.Sort != sort || c.Desc != desc {
+		return nil, fmt.Errorf("cursor does not match sort order")
+	}
+	// The values go into the query as they are, so each must have the type of its
+	// key: a string for text columns, a number (float64 from JSON) for the others
+	for i, k := range keys {
+		var ok bool
+		switch k.value(Task{}).(type) {
+		case string:
+			_, ok = c.Values[i].(string)
+		default:
+			_, ok = c.Values[i].(float64)
+		}
+		if !ok {
+			return nil, fmt.Errorf("invalid cursor")
+		}
+	}
+	return c.Values, nil
+}
+
+// tasksHandler handles GET /api/tasks to retrieve tasks from the scheduler table.
+// The optional search parameter selects tasks on a DD.MM.YYYY date or whose
+// title or comment contain the given text, ignoring case; repeated tag
+// parameters keep only tasks that have all of the tags. sort (date, priority
+// or title) and order (asc or desc) control the ordering. Results are paged
+// with limit and the cursor returned in next_cursor
 func tasksHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		writeError(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Fetch all tasks from the database, ordered by date, replacing NULL with empty strings
-	var tasks []Task
-	err := db.DB.Select(&tasks, `SELECT id, date, title, COALESCE(comment, '') AS comment, COALESCE(repeat, '') AS repeat FROM scheduler ORDER BY date`)
+	limit := defaultTasksLimit
+	if limitStr := r.FormValue("limit"); limitStr != "" {
+		n, err := strconv.Atoi(limitStr)
+		if err != nil || n < 1 {
+			writeError(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, maxTasksLimit)
+	}
+
+	sort := r.FormValue("sort")
+	if sort == "" {
+		sort = "date"
+	}
+	s, ok := taskSorts[sort]
+	if !ok {
+		writeError(w, "invalid sort: must be date, priority or title", http.StatusBadRequest)
+		return
+	}
+	desc := s.desc
+	switch r.FormValue("order") {
+	case "":
+	case "asc":
+		desc = false
+	case "desc":
+		desc = true
+	default:
+		writeError(w, "invalid order: must be asc or desc", http.StatusBadRequest)
+		return
+	}
+	keys := sortKeys(sort, desc)
+	filter := TaskFilter{Sort: sort, Desc: desc, Limit: limit + 1}
+
+	if search := strings.TrimSpace(r.FormValue("search")); search != "" {
+		if date, err := time.Parse(searchDateFormat, search); err == nil {
+			filter.Date = date.Format(dateFormat)
+		} else {
+			filter.Search = search
+		}
+	}
+	tags, err := normalizeTags(r.Form["tag"])
 	if err != nil {
-		writeError(w, "failed to fetch tasks", http.StatusInternalServerError)
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter.Tags = tags
+	if cursor := r.FormValue("cursor"); cursor != "" {
+		filter.After, err = decodeCursor(cursor, sort, desc, keys)
+		if err != nil {
+			writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// The extra task tells whether another page follows
+	tasks, err := service.List(filter)
+	if err != nil {
+		writeServiceError(w, err, "failed to fetch tasks")
+		return
+	}
+
+	var resp TasksResponse
+	if len(tasks) > limit {
+		tasks = tasks[:limit]
+		resp.NextCursor = encodeCursor(sort, desc, keys, tasks[limit-1])
+	}
+	resp.Tasks = listedTasks(r, tasks)
+
+	// Return tasks as JSON in the format {"tasks": [...], "next_cursor": "..."}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.Header().Set("Vary", "Accept-Language")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// overdueHandler handles GET /api/tasks/overdue to list the tasks dated before today, oldest first
+func overdueHandler(w http.ResponseWriter, r *http.Request) {
+	listHandler(w, r, service.Overdue)
+}
+
+// todayHandler handles GET /api/tasks/today to list the tasks due today
+func todayHandler(w http.ResponseWriter, r *http.Request) {
+	listHandler(w, r, service.Today)
+}
+
+// listHandler writes the tasks returned by list in the format of tasksHandler, without paging
+func listHandler(w http.ResponseWriter, r *http.Request, list func() ([]Task, error)) {
+	if r.Method != http.MethodGet {
+		writeError(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Ensure tasks is not nil
-	if tasks == nil {
-		tasks = []Task{}
+	tasks, err := list()
+	if err != nil {
+		writeServiceError(w, err, "failed to fetch tasks")
+		return
 	}
 
-	// Return tasks as JSON in the format {"tasks": [...]}
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.Header().Set("Vary", "Accept-Language")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string][]Task{"tasks": tasks})
+	json.NewEncoder(w).Encode(TasksResponse{Tasks: listedTasks(r, tasks)})
 }
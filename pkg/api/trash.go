@@ -0,0 +1,42 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// trashHandler handles GET /api/trash to list deleted tasks, most recently deleted first
+func trashHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tasks, err := service.Trash()
+	if err != nil {
+		writeServiceError(w, err, "failed to fetch trash")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.Header().Set("Vary", "Accept-Language")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(TasksResponse{Tasks: listedTasks(r, tasks)})
+}
+
+// restoreHandler handles POST /api/task/restore?id=ID to bring a task back from the trash
+func restoreHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := service.Restore(r.FormValue("id")); err != nil {
+		writeServiceError(w, err, "failed to restore task")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{})
+}
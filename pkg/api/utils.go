@@ -2,17 +2,52 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 )
 
-// ErrorResponse defines the structure for error responses
+// ErrorResponse defines the structure for error responses; Code is set for
+// errors clients may want to describe in their own words
 type ErrorResponse struct {
-	Error string `json:"error"`
+	Error string    `json:"error"`
+	Code  ErrorCode `json:"code,omitempty"`
 }
 
 // writeError sends a JSON error response with the specified message and status code
 func writeError(w http.ResponseWriter, msg string, status int) {
+	writeErrorResponse(w, ErrorResponse{Error: msg}, status)
+}
+
+// writeRequestError sends err as a 400 response, with the code of a RepeatError found in it
+func writeRequestError(w http.ResponseWriter, err error) {
+	writeErrorResponse(w, ErrorResponse{Error: err.Error(), Code: errorCode(err)}, http.StatusBadRequest)
+}
+
+func writeErrorResponse(w http.ResponseWriter, resp ErrorResponse, status int) {
 	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
 	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(ErrorResponse{Error: msg})
+	json.NewEncoder(w).Encode(resp)
+}
+
+// errorCode returns the code of a RepeatError in the chain of err, if any
+func errorCode(err error) ErrorCode {
+	var repeatErr *RepeatError
+	if errors.As(err, &repeatErr) {
+		return repeatErr.Code
+	}
+	return ""
+}
+
+// writeServiceError sends the error of a TaskService call: validation errors
+// and unknown ids are reported as is, anything else as msg with status 500
+func writeServiceError(w http.ResponseWriter, err error, msg string) {
+	var invalid *ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		writeRequestError(w, err)
+	case errors.Is(err, ErrTaskNotFound):
+		writeError(w, err.Error(), http.StatusNotFound)
+	default:
+		writeError(w, msg, http.StatusInternalServerError)
+	}
 }
@@ -0,0 +1,250 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"go_final_project/pkg/api"
+	"go_final_project/pkg/db"
+	"go_final_project/pkg/server"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const usage = `Usage: go_final_project [command] [flags]
+
+Commands:
+  serve     run the web server (default when no command is given)
+  add       add a task
+  list      list tasks ordered by date
+  done      complete a task: delete a one-off task or move a repeating one
+  delete    move a task to the trash
+  nextdate  print the next date of a repeat rule
+
+Run "go_final_project <command> -h" for the flags of a command.
+The database is taken from TODO_DBFILE (default scheduler.db).
+`
+
+// taskService is the part of api.TaskService the commands use
+type taskService interface {
+	Create(task api.Task) (string, error)
+	List(filter api.TaskFilter) ([]api.Task, error)
+	Done(id string) error
+	Delete(id string) error
+}
+
+// openService opens the database from TODO_DBFILE and returns a task service on top of it
+func openService() (taskService, error) {
+	if err := db.Init(db.File()); err != nil {
+		return nil, err
+	}
+	loc, err := api.LocationFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	service := api.NewTaskService(api.NewSQLiteStore(db.DB))
+	service.SetLocation(loc)
+	return service, nil
+}
+
+// tagList collects repeated -tag flags
+type tagList []string
+
+func (t *tagList) String() string { return strings.Join(*t, ",") }
+
+func (t *tagList) Set(value string) error {
+	*t = append(*t, value)
+	return nil
+}
+
+// Run executes the command given by args (without the program name) and writes its output to out
+func Run(args []string, out io.Writer) error {
+	err := run(args, out)
+	if errors.Is(err, flag.ErrHelp) {
+		// The flag package has already printed the usage
+		return nil
+	}
+	return err
+}
+
+func run(args []string, out io.Writer) error {
+	// Without a command, or with server flags only, the binary runs the server as before
+	if len(args) == 0 || (strings.HasPrefix(args[0], "-") && args[0] != "-h" && args[0] != "--help") {
+		return serve(args)
+	}
+
+	cmd, args := args[0], args[1:]
+	switch cmd {
+	case "serve":
+		return serve(args)
+	case "add":
+		return add(args, out)
+	case "list":
+		return list(args, out)
+	case "done":
+		return withID("done", args, out, taskService.Done)
+	case "delete":
+		return withID("delete", args, out, taskService.Delete)
+	case "nextdate":
+		return nextDate(args, out)
+	case "help", "-h", "--help":
+		fmt.Fprint(out, usage)
+		return nil
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("unknown command: %s", cmd)
+	}
+}
+
+// serve runs the web server configured from the environment and flags
+func serve(args []string) error {
+	cfg, err := server.ConfigFromEnv()
+	if err != nil {
+		return err
+	}
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.StringVar(&cfg.Addr, "addr", cfg.Addr, "address to listen on")
+	fs.StringVar(&cfg.WebDir, "web", cfg.WebDir, "directory with frontend files")
+	fs.DurationVar(&cfg.ReadTimeout, "read-timeout", cfg.ReadTimeout, "maximum duration for reading a request")
+	fs.DurationVar(&cfg.WriteTimeout, "write-timeout", cfg.WriteTimeout, "maximum duration for writing a response")
+	fs.DurationVar(&cfg.IdleTimeout, "idle-timeout", cfg.IdleTimeout, "how long to keep idle connections open")
+	fs.DurationVar(&cfg.ShutdownTimeout, "shutdown-timeout", cfg.ShutdownTimeout, "how long to wait for requests on shutdown")
+	fs.Func("tz", "time zone that decides which day is today, e.g. Europe/Moscow", func(name string) error {
+		loc, err := time.LoadLocation(name)
+		cfg.Location = loc
+		return err
+	})
+	fs.DurationVar(&cfg.TrashRetention, "trash-retention", cfg.TrashRetention, "how long deleted tasks stay in the trash, 0 to keep them")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if err := db.Init(db.File()); err != nil {
+		return err
+	}
+	return server.Run(cfg)
+}
+
+// add creates a task with the same checks as POST /api/task and prints its id
+func add(args []string, out io.Writer) error {
+	var task api.Task
+	var tags tagList
+	fs := flag.NewFlagSet("add", flag.ContinueOnError)
+	fs.StringVar(&task.Title, "title", "", "task title (required)")
+	fs.StringVar(&task.Date, "date", "", "date as YYYYMMDD (default today)")
+	fs.StringVar(&task.Time, "time", "", "time of day as HH:MM (default all day)")
+	fs.IntVar(&task.Duration, "duration", 0, "duration in minutes, requires -time")
+	fs.StringVar(&task.Comment, "comment", "", "comment")
+	fs.StringVar(&task.Repeat, "repeat", "", `repeat rule, e.g. "d 7", "y", "w 1,3", "m -1"`)
+	fs.IntVar(&task.Priority, "priority", 0, "priority from 0 (none) to 3 (most urgent)")
+	fs.Var(&tags, "tag", "tag, may be repeated")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	task.Tags = tags
+
+	svc, err := openService()
+	if err != nil {
+		return err
+	}
+	defer db.DB.Close()
+
+	id, err := svc.Create(task)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, id)
+	return nil
+}
+
+// list prints all tasks as a table
+func list(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	svc, err := openService()
+	if err != nil {
+		return err
+	}
+	defer db.DB.Close()
+
+	tasks, err := svc.List(api.TaskFilter{})
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tDATE\tTIME\tTITLE\tREPEAT\tPRIORITY\tTAGS")
+	for _, task := range tasks {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\t%s\n",
+			task.ID, task.Date, task.Time, task.Title, task.Repeat, task.Priority, strings.Join(task.Tags, ","))
+	}
+	return tw.Flush()
+}
+
+// withID runs an action that takes a single task id argument
+func withID(name string, args []string, out io.Writer, action func(svc taskService, id string) error) error {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: go_final_project %s ID\n", name)
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("task id is required")
+	}
+
+	svc, err := openService()
+	if err != nil {
+		return err
+	}
+	defer db.DB.Close()
+
+	if err := action(svc, fs.Arg(0)); err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "ok")
+	return nil
+}
+
+// nextDate prints the result of api.NextDate like GET /api/nextdate
+func nextDate(args []string, out io.Writer) error {
+	var nowStr, date, repeat string
+	fs := flag.NewFlagSet("nextdate", flag.ContinueOnError)
+	fs.StringVar(&nowStr, "now", "", "current date as YYYYMMDD (default today)")
+	fs.StringVar(&date, "date", "", "start date as YYYYMMDD (required)")
+	fs.StringVar(&repeat, "repeat", "", "repeat rule")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if date == "" {
+		return errors.New("-date is required")
+	}
+
+	loc, err := api.LocationFromEnv()
+	if err != nil {
+		return err
+	}
+	now := time.Now().In(loc)
+	if nowStr != "" {
+		parsed, err := time.Parse("20060102", nowStr)
+		if err != nil {
+			return fmt.Errorf("invalid now date format: %v", err)
+		}
+		now = parsed
+	}
+
+	next, err := api.NextDate(now, date, repeat)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, next)
+	return nil
+}
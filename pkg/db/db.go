@@ -1,36 +1,57 @@
 package db
 
 import (
+	"database/sql/driver"
 	"os"
+	"strings"
 
 	"github.com/jmoiron/sqlx"
-	_ "modernc.org/sqlite"
+	"modernc.org/sqlite"
 )
 
 var DB *sqlx.DB
 
-const schema = `
-CREATE TABLE IF NOT EXISTS scheduler (
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    date CHAR(8) NOT NULL DEFAULT '',
-    title VARCHAR(255) NOT NULL,
-    comment TEXT,
-    repeat VARCHAR(128) DEFAULT ''
-);
-CREATE INDEX IF NOT EXISTS idx_date ON scheduler(date);
-`
+// DefaultFile is the database used when TODO_DBFILE is not set
+const DefaultFile = "scheduler.db"
 
-func Init(dbFile string) error {
-	_, err := os.Stat(dbFile)
-	install := err != nil
+// SQLite's lower() and LIKE only fold ASCII, so register a Unicode-aware
+// lower() for case-insensitive search over Cyrillic text
+func init() {
+	sqlite.MustRegisterDeterministicScalarFunction("unicode_lower", 1,
+		func(ctx *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+			switch v := args[0].(type) {
+			case string:
+				return strings.ToLower(v), nil
+			case []byte:
+				return strings.ToLower(string(v)), nil
+			default:
+				return v, nil
+			}
+		})
+}
 
-	DB, err = sqlx.Open("sqlite", dbFile)
-	if err != nil {
-		return err
+// File returns the database path from TODO_DBFILE, falling back to DefaultFile
+func File() string {
+	if dbFile := os.Getenv("TODO_DBFILE"); dbFile != "" {
+		return dbFile
 	}
+	return DefaultFile
+}
 
-	if install {
-		_, err = DB.Exec(schema)
+// Init opens the database and brings its schema up to date
+func Init(dbFile string) error {
+	var err error
+	// Foreign keys are off by default in SQLite; they remove the tags of deleted tasks.
+	// Transactions take the write lock up front and wait for it instead of failing
+	// with SQLITE_BUSY, so concurrent read-then-write transactions queue up.
+	// TODO_DBFILE may already be a URI with its own query string
+	sep := "?"
+	if strings.Contains(dbFile, "?") {
+		sep = "&"
+	}
+	DB, err = sqlx.Open("sqlite", dbFile+sep+"_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_txlock=immediate")
+	if err != nil {
+		return err
 	}
-	return err
+	return migrate()
 }
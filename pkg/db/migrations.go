@@ -0,0 +1,101 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// migrations lists schema changes in order; migration N brings the database to version N+1.
+// Append new steps to the end and never edit steps that have already shipped
+var migrations = []string{
+	// 1: initial scheduler table, matching databases created before migrations existed
+	`CREATE TABLE IF NOT EXISTS scheduler (
+	    id INTEGER PRIMARY KEY AUTOINCREMENT,
+	    date CHAR(8) NOT NULL DEFAULT '',
+	    title VARCHAR(255) NOT NULL,
+	    comment TEXT,
+	    repeat VARCHAR(128) DEFAULT ''
+	);
+	CREATE INDEX IF NOT EXISTS idx_date ON scheduler(date);`,
+
+	// 2: task priority, 0 (none) to 3 (most urgent)
+	`ALTER TABLE scheduler ADD COLUMN priority INTEGER NOT NULL DEFAULT 0;`,
+
+	// 3: tags linked to tasks many-to-many
+	`CREATE TABLE tags (
+	    id INTEGER PRIMARY KEY AUTOINCREMENT,
+	    name VARCHAR(64) NOT NULL UNIQUE
+	);
+	CREATE TABLE task_tags (
+	    task_id INTEGER NOT NULL REFERENCES scheduler(id) ON DELETE CASCADE,
+	    tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+	    PRIMARY KEY (task_id, tag_id)
+	);
+	CREATE INDEX idx_task_tags_tag ON task_tags(tag_id);`,
+
+	// 4: soft delete; deleted_at holds the RFC 3339 time a task went to the trash
+	`ALTER TABLE scheduler ADD COLUMN deleted_at TEXT;
+	CREATE INDEX idx_deleted_at ON scheduler(deleted_at);`,
+
+	// 5: completion history; date is the scheduled date, completed_at the RFC 3339 time of completion
+	`CREATE TABLE completions (
+	    id INTEGER PRIMARY KEY AUTOINCREMENT,
+	    task_id INTEGER NOT NULL REFERENCES scheduler(id) ON DELETE CASCADE,
+	    date CHAR(8) NOT NULL,
+	    completed_at TEXT NOT NULL
+	);
+	CREATE INDEX idx_completions_task ON completions(task_id, completed_at);`,
+
+	// 6: optional time of day, HH:MM or empty for all-day tasks, and duration in minutes
+	`ALTER TABLE scheduler ADD COLUMN time CHAR(5) NOT NULL DEFAULT '';
+	ALTER TABLE scheduler ADD COLUMN duration INTEGER NOT NULL DEFAULT 0;`,
+
+	// 7: completed occurrences of a repeating task, for rules ending with "count N"
+	`ALTER TABLE scheduler ADD COLUMN done_count INTEGER NOT NULL DEFAULT 0;`,
+
+	// 8: finished_at holds the RFC 3339 time the final occurrence of a repeating task was done;
+	// finished tasks leave the schedule but keep their completion history
+	`ALTER TABLE scheduler ADD COLUMN finished_at TEXT;`,
+}
+
+// migrate applies every migration newer than the version recorded in schema_migrations
+func migrate() error {
+	_, err := DB.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+	    version INTEGER PRIMARY KEY,
+	    applied_at TEXT NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	var version int
+	if err := DB.Get(&version, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`); err != nil {
+		return fmt.Errorf("read schema version: %w", err)
+	}
+
+	for i := version; i < len(migrations); i++ {
+		if err := applyMigration(i+1, migrations[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyMigration runs one migration and records its version in a single transaction
+func applyMigration(version int, stmt string) error {
+	tx, err := DB.Beginx()
+	if err != nil {
+		return fmt.Errorf("migration %d: %w", version, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(stmt); err != nil {
+		return fmt.Errorf("migration %d: %w", version, err)
+	}
+	_, err = tx.Exec(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`,
+		version, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("migration %d: %w", version, err)
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// baselineSchema is the schema databases had before migrations existed
+const baselineSchema = `CREATE TABLE scheduler (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    date CHAR(8) NOT NULL DEFAULT '',
+    title VARCHAR(255) NOT NULL,
+    comment TEXT,
+    repeat VARCHAR(128) DEFAULT ''
+);
+CREATE INDEX idx_date ON scheduler(date);`
+
+// initDB runs Init on dbFile and closes the database when the test ends
+func initDB(t *testing.T, dbFile string) {
+	require.NoError(t, Init(dbFile))
+	conn := DB
+	t.Cleanup(func() { conn.Close() })
+}
+
+// columns returns the column names of a table, empty if the table doesn't exist
+func columns(t *testing.T, table string) []string {
+	var names []string
+	require.NoError(t, DB.Select(&names, `SELECT name FROM pragma_table_info(?)`, table))
+	return names
+}
+
+// appliedVersions returns the versions recorded in schema_migrations
+func appliedVersions(t *testing.T) []int {
+	var versions []int
+	require.NoError(t, DB.Select(&versions, `SELECT version FROM schema_migrations ORDER BY version`))
+	return versions
+}
+
+func TestMigrateBaseline(t *testing.T) {
+	dbFile := filepath.Join(t.TempDir(), "scheduler.db")
+	old, err := sqlx.Open("sqlite", dbFile)
+	require.NoError(t, err)
+	_, err = old.Exec(baselineSchema)
+	require.NoError(t, err)
+	_, err = old.Exec(`INSERT INTO scheduler (date, title, comment, repeat) VALUES ('20240126', 'Старая задача', '', 'd 7')`)
+	require.NoError(t, err)
+	require.NoError(t, old.Close())
+
+	initDB(t, dbFile)
+
+	var want []int
+	for i := range migrations {
+		want = append(want, i+1)
+	}
+	assert.Equal(t, want, appliedVersions(t))
+	assert.Equal(t, []string{"id", "date", "title", "comment", "repeat",
+		"priority", "deleted_at", "time", "duration", "done_count", "finished_at"}, columns(t, "scheduler"))
+	assert.Equal(t, []string{"id", "name"}, columns(t, "tags"))
+	assert.Equal(t, []string{"task_id", "tag_id"}, columns(t, "task_tags"))
+	assert.Equal(t, []string{"id", "task_id", "date", "completed_at"}, columns(t, "completions"))
+
+	// Existing tasks are kept and get the defaults of the new columns
+	var task struct {
+		Title     string  `db:"title"`
+		Repeat    string  `db:"repeat"`
+		Priority  int     `db:"priority"`
+		DeletedAt *string `db:"deleted_at"`
+		Time      string  `db:"time"`
+		DoneCount int     `db:"done_count"`
+	}
+	require.NoError(t, DB.Get(&task, `SELECT title, repeat, priority, deleted_at, time, done_count FROM scheduler`))
+	assert.Equal(t, "Старая задача", task.Title)
+	assert.Equal(t, "d 7", task.Repeat)
+	assert.Zero(t, task.Priority)
+	assert.Nil(t, task.DeletedAt)
+	assert.Empty(t, task.Time)
+	assert.Zero(t, task.DoneCount)
+}
+
+func TestMigrateTwice(t *testing.T) {
+	dbFile := filepath.Join(t.TempDir(), "scheduler.db")
+	initDB(t, dbFile)
+	var applied []string
+	require.NoError(t, DB.Select(&applied, `SELECT applied_at FROM schema_migrations ORDER BY version`))
+	schedulerColumns := columns(t, "scheduler")
+
+	// A second Init on an up-to-date database changes nothing
+	initDB(t, dbFile)
+	var again []string
+	require.NoError(t, DB.Select(&again, `SELECT applied_at FROM schema_migrations ORDER BY version`))
+	assert.Equal(t, applied, again)
+	assert.Len(t, appliedVersions(t), len(migrations))
+	assert.Equal(t, schedulerColumns, columns(t, "scheduler"))
+}
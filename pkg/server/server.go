@@ -1,19 +1,166 @@
 package server
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"go_final_project/pkg/api"
+	"go_final_project/pkg/db"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 )
 
-func Run() error {
-	port := os.Getenv("TODO_PORT")
-	if port == "" {
-		port = "7540"
+// Config holds the HTTP server settings
+type Config struct {
+	Addr            string         // address to listen on, e.g. ":7540"
+	WebDir          string         // directory with the frontend files
+	ReadTimeout     time.Duration  // maximum time to read a request
+	WriteTimeout    time.Duration  // maximum time to write a response
+	IdleTimeout     time.Duration  // how long keep-alive connections stay open
+	ShutdownTimeout time.Duration  // how long to wait for in-flight requests on shutdown
+	TrashRetention  time.Duration  // how long deleted tasks stay in the trash; 0 keeps them forever
+	Location        *time.Location // time zone that decides which day is today
+}
+
+// purgeInterval is how often the trash is checked for expired tasks
+const purgeInterval = time.Hour
+
+// ConfigFromEnv returns the default configuration overridden by TODO_* environment variables
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		Addr:            ":7540",
+		WebDir:          "./web",
+		ReadTimeout:     10 * time.Second,
+		WriteTimeout:    10 * time.Second,
+		IdleTimeout:     60 * time.Second,
+		ShutdownTimeout: 15 * time.Second,
+		TrashRetention:  30 * 24 * time.Hour,
+	}
+
+	if port := os.Getenv("TODO_PORT"); port != "" {
+		cfg.Addr = ":" + port
+	}
+	if addr := os.Getenv("TODO_ADDR"); addr != "" {
+		cfg.Addr = addr
+	}
+	if dir := os.Getenv("TODO_WEBDIR"); dir != "" {
+		cfg.WebDir = dir
+	}
+	loc, err := api.LocationFromEnv()
+	if err != nil {
+		return cfg, err
+	}
+	cfg.Location = loc
+
+	durations := []struct {
+		env string
+		dst *time.Duration
+	}{
+		{"TODO_READ_TIMEOUT", &cfg.ReadTimeout},
+		{"TODO_WRITE_TIMEOUT", &cfg.WriteTimeout},
+		{"TODO_IDLE_TIMEOUT", &cfg.IdleTimeout},
+		{"TODO_SHUTDOWN_TIMEOUT", &cfg.ShutdownTimeout},
+		{"TODO_TRASH_RETENTION", &cfg.TrashRetention},
+	}
+	for _, d := range durations {
+		value := os.Getenv(d.env)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid %s: %v", d.env, err)
+		}
+		*d.dst = parsed
+	}
+	return cfg, nil
+}
+
+// Run serves the API and frontend until SIGINT or SIGTERM, then drains
+// in-flight requests and closes the database
+func Run(cfg Config) error {
+	service := api.NewTaskService(api.NewSQLiteStore(db.DB))
+	if cfg.Location != nil {
+		service.SetLocation(cfg.Location)
+	}
+	api.Init(service) // Register API handlers
+	http.Handle("/", http.FileServer(http.Dir(cfg.WebDir)))
+
+	srv := &http.Server{
+		Addr:         cfg.Addr,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var purging sync.WaitGroup
+	if cfg.TrashRetention > 0 {
+		purging.Add(1)
+		go func() {
+			defer purging.Done()
+			purgeTrash(ctx, service, cfg.TrashRetention)
+		}()
+	}
+	// closeDB stops the trash purge and waits for a purge in progress to finish,
+	// so the database isn't closed under a running query
+	closeDB := func() error {
+		stop()
+		purging.Wait()
+		return db.DB.Close()
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		log.Printf("Server starting on %s", cfg.Addr)
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		// The server failed to start or stopped on its own
+		closeDB()
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Println("Shutting down server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	err := srv.Shutdown(shutdownCtx)
+	if closeErr := closeDB(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// purgeTrash removes expired tasks from the trash right away and then every
+// purgeInterval until ctx is cancelled
+func purgeTrash(ctx context.Context, service *api.TaskService, retention time.Duration) {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+	for {
+		n, err := service.PurgeTrash(retention)
+		if err != nil {
+			log.Printf("Failed to purge trash: %v", err)
+		} else if n > 0 {
+			log.Printf("Purged %d tasks from the trash", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
 	}
-	api.Init() // Register API handlers
-	http.Handle("/", http.FileServer(http.Dir("./web")))
-	log.Printf("Server starting on port %s", port)
-	return http.ListenAndServe(":"+port, nil)
 }
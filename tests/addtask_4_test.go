@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/http/cookiejar"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -60,6 +61,28 @@ func requestJSON(apipath string, values map[string]any, method string) ([]byte,
 	return io.ReadAll(resp.Body)
 }
 
+// requestWithHeaders sends body with the given headers and the token cookie and
+// returns the response body, for requests that aren't JSON or need more headers
+func requestWithHeaders(apipath, method string, headers map[string]string, body string) ([]byte, error) {
+	req, err := http.NewRequest(method, getURL(apipath), strings.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	if len(Token) > 0 {
+		req.AddCookie(&http.Cookie{Name: "token", Value: Token})
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
 func postJSON(apipath string, values map[string]any, method string) (map[string]any, error) {
 	var (
 		m   map[string]any
@@ -0,0 +1,41 @@
+package tests
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalendarExport(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	date := time.Now().AddDate(0, 0, 1).Format(`20060102`)
+	weekly := addTask(t, task{
+		date:    date,
+		title:   "Йога",
+		comment: "Коврик, вода; полотенце",
+		repeat:  "w 1,3",
+	})
+	monthly := addTask(t, task{
+		date:   date,
+		title:  "Аренда",
+		repeat: "m -1 1,4",
+	})
+
+	body, err := requestJSON("api/calendar.ics", nil, http.MethodGet)
+	assert.NoError(t, err)
+	ics := string(body)
+
+	assert.True(t, strings.HasPrefix(ics, "BEGIN:VCALENDAR\r\n"))
+	assert.True(t, strings.HasSuffix(ics, "END:VCALENDAR\r\n"))
+	assert.Contains(t, ics, "UID:task-"+weekly+"@")
+	assert.Contains(t, ics, "DTSTART;VALUE=DATE:"+date)
+	assert.Contains(t, ics, `DESCRIPTION:Коврик\, вода\; полотенце`)
+	assert.Contains(t, ics, "RRULE:FREQ=WEEKLY;BYDAY=MO,WE")
+	assert.Contains(t, ics, "UID:task-"+monthly+"@")
+	assert.Contains(t, ics, "RRULE:FREQ=MONTHLY;BYMONTHDAY=-1;BYMONTH=1,4")
+}
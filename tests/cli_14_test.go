@@ -0,0 +1,56 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func runCLI(t *testing.T, args ...string) (string, error) {
+	dbfile := DBFile
+	if envFile := os.Getenv("TODO_DBFILE"); len(envFile) > 0 {
+		dbfile = envFile
+	}
+	cmd := exec.Command("go", append([]string{"run", ".."}, args...)...)
+	cmd.Env = append(os.Environ(), "TODO_DBFILE="+dbfile)
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+func TestCLI(t *testing.T) {
+	next, err := runCLI(t, "nextdate", "-now", "20240126", "-date", "20240113", "-repeat", "d 7")
+	assert.NoError(t, err)
+	assert.Equal(t, "20240127", next)
+
+	_, err = runCLI(t, "add", "-title", "Плохая задача", "-repeat", "ooops")
+	assert.Error(t, err)
+
+	now := time.Now()
+	id, err := runCLI(t, "add", "-title", "Из консоли", "-repeat", "d 2", "-tag", "cli")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, id)
+
+	list, err := runCLI(t, "list")
+	assert.NoError(t, err)
+	assert.Contains(t, list, "Из консоли")
+
+	_, err = runCLI(t, "done", id)
+	assert.NoError(t, err)
+
+	body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var m map[string]any
+	assert.NoError(t, json.Unmarshal(body, &m))
+	assert.Equal(t, now.AddDate(0, 0, 2).Format(`20060102`), m["date"])
+	assert.Equal(t, []any{"cli"}, m["tags"])
+
+	_, err = runCLI(t, "delete", id)
+	assert.NoError(t, err)
+	notFoundTask(t, id)
+}
@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"database/sql"
 	"os"
 	"testing"
 	"time"
@@ -11,11 +12,17 @@ import (
 )
 
 type Task struct {
-	ID      int64  `db:"id"`
-	Date    string `db:"date"`
-	Title   string `db:"title"`
-	Comment string `db:"comment"`
-	Repeat  string `db:"repeat"`
+	ID         int64          `db:"id"`
+	Date       string         `db:"date"`
+	Title      string         `db:"title"`
+	Comment    string         `db:"comment"`
+	Repeat     string         `db:"repeat"`
+	Priority   int            `db:"priority"`
+	DeletedAt  sql.NullString `db:"deleted_at"`
+	Time       string         `db:"time"`
+	Duration   int            `db:"duration"`
+	DoneCount  int            `db:"done_count"`
+	FinishedAt sql.NullString `db:"finished_at"`
 }
 
 func count(db *sqlx.DB) (int, error) {
@@ -0,0 +1,80 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type importResult struct {
+	Imported int      `json:"imported"`
+	IDs      []string `json:"ids"`
+	Errors   []struct {
+		Index int    `json:"index"`
+		Error string `json:"error"`
+	} `json:"errors"`
+	Error string `json:"error"`
+}
+
+func postImport(t *testing.T, query, contentType, data string) importResult {
+	body, err := requestWithHeaders("api/import?"+query, http.MethodPost,
+		map[string]string{"Content-Type": contentType}, data)
+	assert.NoError(t, err)
+
+	var res importResult
+	assert.NoError(t, json.Unmarshal(body, &res))
+	return res
+}
+
+func TestExportImport(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	_, err := db.Exec("DELETE FROM scheduler")
+	assert.NoError(t, err)
+
+	date := time.Now().AddDate(0, 0, 2).Format(`20060102`)
+	addTask(t, task{date: date, title: "Экспорт, CSV", comment: "строка\nвторая", repeat: "d 2"})
+	addTask(t, task{date: date, title: "Экспорт JSON"})
+
+	csvBody, err := requestJSON("api/export?format=csv", nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(csvBody), "id,date,title,comment,repeat,priority,tags,time,duration,done_count\n"))
+
+	jsonBody, err := requestJSON("api/export?format=json", nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	// A dry run reports problems without saving anything
+	bad := "title,date,repeat\nХорошая,,\nПлохая,20240192,\nЕщё плохая,,ooops\n"
+	res := postImport(t, "format=csv&dry_run=1", "text/csv", bad)
+	if assert.Len(t, res.Errors, 2) {
+		assert.Equal(t, 2, res.Errors[0].Index)
+		assert.Equal(t, 3, res.Errors[1].Index)
+	}
+	res = postImport(t, "format=csv", "text/csv", bad)
+	assert.NotEmpty(t, res.Error)
+	assert.Equal(t, 0, res.Imported)
+	before, err := count(db)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, before)
+
+	res = postImport(t, "format=csv", "text/csv", string(csvBody))
+	assert.Empty(t, res.Errors)
+	assert.Equal(t, 2, res.Imported)
+	res = postImport(t, "", "application/json", string(jsonBody))
+	assert.Empty(t, res.Errors)
+	assert.Equal(t, 2, res.Imported)
+
+	var tasks []Task
+	assert.NoError(t, db.Select(&tasks, `SELECT * FROM scheduler WHERE title = ?`, "Экспорт, CSV"))
+	assert.Len(t, tasks, 3)
+	for _, v := range tasks {
+		assert.Equal(t, "строка\nвторая", v.Comment)
+		assert.Equal(t, "d 2", v.Repeat)
+		assert.Equal(t, date, v.Date)
+	}
+}
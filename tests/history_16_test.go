@@ -0,0 +1,53 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type historyResponse struct {
+	Completions []map[string]string `json:"completions"`
+}
+
+func TestHistory(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	id := addTask(t, task{
+		date:   now.Format(`20060102`),
+		title:  "Полить цветы",
+		repeat: "d 3",
+	})
+
+	for i := 0; i < 2; i++ {
+		ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+	}
+
+	body, err := requestJSON("api/task/history?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var history historyResponse
+	assert.NoError(t, json.Unmarshal(body, &history))
+	if assert.Len(t, history.Completions, 2) {
+		assert.Equal(t, now.AddDate(0, 0, 3).Format(`20060102`), history.Completions[0]["date"])
+		assert.Equal(t, now.Format(`20060102`), history.Completions[1]["date"])
+		for _, c := range history.Completions {
+			completed, err := time.Parse(time.RFC3339, c["completed_at"])
+			assert.NoError(t, err)
+			assert.WithinDuration(t, now, completed, time.Minute)
+		}
+	}
+
+	ret, err := postJSON("api/task/history", nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+	ret, err = postJSON("api/task/history?id=100500100", nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+}
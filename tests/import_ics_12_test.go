@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testICS = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:daily@test\r\n" +
+	"DTSTART;VALUE=DATE:20240126\r\n" +
+	"SUMMARY:Полить цветы\r\n" +
+	"DESCRIPTION:На кухне\\, и в спальне\r\n" +
+	"RRULE:FREQ=DAILY;INTERVAL=3\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:weekly@test\r\n" +
+	"DTSTART;TZID=Europe/Moscow:20240129T100000\r\n" +
+	"SUMMARY:Спортзал\r\n" +
+	"RRULE:FREQ=WEEKLY;BYDAY=MO,WE,FR\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"SUMMARY:Напоминание\r\n" +
+	"END:VALARM\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:hourly@test\r\n" +
+	"DTSTART;VALUE=DATE:20240126\r\n" +
+	"SUMMARY:Пить воду\r\n" +
+	"RRULE:FREQ=HOURLY;INTERVAL=2\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestImportICS(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	body, err := requestWithHeaders("api/import/ics", http.MethodPost,
+		map[string]string{"Content-Type": "text/calendar"}, testICS)
+	assert.NoError(t, err)
+
+	var m struct {
+		Imported int      `json:"imported"`
+		IDs      []string `json:"ids"`
+		Errors   []struct {
+			Index int    `json:"index"`
+			UID   string `json:"uid"`
+			Error string `json:"error"`
+		} `json:"errors"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &m))
+	assert.Equal(t, 2, m.Imported)
+	if assert.Len(t, m.Errors, 1) {
+		assert.Equal(t, 3, m.Errors[0].Index)
+		assert.Equal(t, "hourly@test", m.Errors[0].UID)
+		assert.NotEmpty(t, m.Errors[0].Error)
+	}
+	if !assert.Len(t, m.IDs, 2) {
+		return
+	}
+
+	var task Task
+	assert.NoError(t, db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, m.IDs[0]))
+	assert.Equal(t, "Полить цветы", task.Title)
+	assert.Equal(t, "На кухне, и в спальне", task.Comment)
+	assert.Equal(t, "d 3", task.Repeat)
+
+	assert.NoError(t, db.Get(&task, `SELECT * FROM scheduler WHERE id=?`, m.IDs[1]))
+	assert.Equal(t, "Спортзал", task.Title)
+	assert.Equal(t, "w 1,3,5", task.Repeat)
+}
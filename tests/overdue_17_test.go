@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func overdueFlags(t *testing.T, path string) map[string]any {
+	body, err := requestJSON(path, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var page tasksPage
+	assert.NoError(t, json.Unmarshal(body, &page))
+	titles := make(map[string]any)
+	for _, v := range page.Tasks {
+		titles[fmt.Sprint(v["title"])] = v["overdue"]
+	}
+	return titles
+}
+
+func TestOverdue(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	_, err := db.Exec("DELETE FROM scheduler")
+	assert.NoError(t, err)
+
+	// The API moves past dates to today, so an overdue task has to be stored directly
+	now := time.Now()
+	_, err = db.Exec(`INSERT INTO scheduler (date, title, comment, repeat) VALUES (?, ?, '', '')`,
+		now.AddDate(0, 0, -2).Format(`20060102`), "Пропущено")
+	assert.NoError(t, err)
+	id := addTask(t, task{date: now.Format(`20060102`), title: "На сегодня"})
+	addTask(t, task{date: now.AddDate(0, 0, 1).Format(`20060102`), title: "На завтра"})
+
+	assert.Equal(t, map[string]any{"Пропущено": true}, overdueFlags(t, "api/tasks/overdue"))
+	assert.Equal(t, map[string]any{"На сегодня": false}, overdueFlags(t, "api/tasks/today"))
+	assert.Equal(t, map[string]any{"Пропущено": true, "На сегодня": false, "На завтра": false},
+		overdueFlags(t, "api/tasks"))
+
+	// Exports and single tasks don't have the field
+	body, err := requestJSON("api/export?format=json", nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(body), "overdue")
+	body, err = requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(body), "overdue")
+
+	_, err = db.Exec("DELETE FROM scheduler")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,94 @@
+package tests
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type tasksPage struct {
+	Tasks      []map[string]any `json:"tasks"`
+	NextCursor string           `json:"next_cursor"`
+}
+
+func getTasksPage(t *testing.T, limit, cursor string) tasksPage {
+	body, err := requestJSON("api/tasks?limit="+limit+"&cursor="+url.QueryEscape(cursor), nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var page tasksPage
+	err = json.Unmarshal(body, &page)
+	assert.NoError(t, err)
+	return page
+}
+
+func TestTasksPagination(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	_, err := db.Exec("DELETE FROM scheduler")
+	assert.NoError(t, err)
+
+	now := time.Now()
+	var want []string
+	for i := 0; i < 5; i++ {
+		date := now.AddDate(0, 0, i%3).Format(`20060102`)
+		id := addTask(t, task{date: date, title: "Страница"})
+		want = append(want, date+"/"+id)
+	}
+	// Pages follow date, then id, with every task exactly once
+	sort.Slice(want, func(i, j int) bool {
+		di, idi, _ := strings.Cut(want[i], "/")
+		dj, idj, _ := strings.Cut(want[j], "/")
+		if di != dj {
+			return di < dj
+		}
+		ni, _ := strconv.Atoi(idi)
+		nj, _ := strconv.Atoi(idj)
+		return ni < nj
+	})
+
+	var got []string
+	cursor := ""
+	for pages := 0; pages < 5; pages++ {
+		page := getTasksPage(t, "2", cursor)
+		assert.LessOrEqual(t, len(page.Tasks), 2)
+		for _, v := range page.Tasks {
+			got = append(got, fmt.Sprint(v["date"])+"/"+fmt.Sprint(v["id"]))
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	assert.Equal(t, want, got)
+	assert.Equal(t, 5, len(getTasks(t, "")))
+
+	body, err := requestJSON("api/tasks?limit=abc", nil, http.MethodGet)
+	assert.NoError(t, err)
+	var m map[string]any
+	assert.NoError(t, json.Unmarshal(body, &m))
+	assert.NotEmpty(t, m["error"])
+
+	// Cursor values of the wrong type are rejected before they reach the query
+	for _, raw := range []string{
+		`{"s":"date","v":[{"a":1},"",1]}`,
+		`{"s":"date","v":["20240126","","1"]}`,
+		`{"s":"date","v":[20240126,"",1]}`,
+	} {
+		cursor := base64.RawURLEncoding.EncodeToString([]byte(raw))
+		body, err = requestJSON("api/tasks?cursor="+cursor, nil, http.MethodGet)
+		assert.NoError(t, err)
+		m = nil
+		assert.NoError(t, json.Unmarshal(body, &m))
+		assert.Equal(t, "invalid cursor", m["error"], raw)
+	}
+}
@@ -0,0 +1,38 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextDatePreview(t *testing.T) {
+	preview := func(query url.Values) map[string]any {
+		body, err := requestJSON("api/nextdate/preview?"+query.Encode(), nil, http.MethodGet)
+		assert.NoError(t, err)
+		var m map[string]any
+		assert.NoError(t, json.Unmarshal(body, &m))
+		return m
+	}
+
+	m := preview(url.Values{"now": {"20240126"}, "date": {"20240120"}, "repeat": {"m 1,15"}, "n": {"4"}})
+	assert.Equal(t, []any{"20240201", "20240215", "20240301", "20240315"}, m["dates"])
+
+	m = preview(url.Values{"now": {"20240126"}, "date": {"20240126"}, "repeat": {"w 6,7 count 3"}})
+	assert.Equal(t, []any{"20240126", "20240127", "20240128"}, m["dates"])
+
+	m = preview(url.Values{"now": {"20240126"}, "date": {"20240126"}, "repeat": {"d 1"}})
+	assert.Len(t, m["dates"], 10)
+
+	for _, query := range []url.Values{
+		{"date": {"20240126"}, "repeat": {"d 500"}},
+		{"date": {"20240126"}, "repeat": {"d 1"}, "n": {"0"}},
+		{"date": {"2024"}, "repeat": {"d 1"}},
+	} {
+		m = preview(query)
+		assert.NotEmpty(t, m["error"], query.Encode())
+	}
+}
@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskPriority(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	_, err := db.Exec("DELETE FROM scheduler")
+	assert.NoError(t, err)
+
+	date := time.Now().Format(`20060102`)
+	for _, priority := range []int{1, 3, 0, 2} {
+		ret, err := postJSON("api/task", map[string]any{
+			"date":     date,
+			"title":    fmt.Sprintf("Приоритет %d", priority),
+			"priority": priority,
+		}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotNil(t, ret["id"])
+	}
+
+	ret, err := postJSON("api/task", map[string]any{
+		"date":     date,
+		"title":    "Слишком срочно",
+		"priority": 4,
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	titles := func(url string) []string {
+		var list []string
+		for pages := 0; pages < 5 && url != ""; pages++ {
+			body, err := requestJSON(url, nil, http.MethodGet)
+			assert.NoError(t, err)
+			var page tasksPage
+			assert.NoError(t, json.Unmarshal(body, &page))
+			for _, v := range page.Tasks {
+				list = append(list, fmt.Sprint(v["title"]))
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			url = "api/tasks?limit=1&sort=priority&order=asc&cursor=" + page.NextCursor
+		}
+		return list
+	}
+
+	assert.Equal(t, []string{"Приоритет 3", "Приоритет 2", "Приоритет 1", "Приоритет 0"},
+		titles("api/tasks?sort=priority"))
+	assert.Equal(t, []string{"Приоритет 0", "Приоритет 1", "Приоритет 2", "Приоритет 3"},
+		titles("api/tasks?limit=1&sort=priority&order=asc"))
+
+	body, err := requestJSON("api/tasks?sort=color", nil, http.MethodGet)
+	assert.NoError(t, err)
+	var m map[string]any
+	assert.NoError(t, json.Unmarshal(body, &m))
+	assert.NotEmpty(t, m["error"])
+}
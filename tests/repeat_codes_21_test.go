@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepeatErrorCodes(t *testing.T) {
+	tbl := []struct {
+		repeat string
+		code   string
+	}{
+		{"k 34", "unknown_rule"},
+		{"d 401", "bad_interval"},
+		{"w 8", "day_out_of_range"},
+		{"m 32", "day_out_of_range"},
+		{"m 1 13", "month_out_of_range"},
+		{"d", "missing_argument"},
+		{"d 7 count 0", "bad_end"},
+	}
+	for _, v := range tbl {
+		ret, err := postJSON("api/task", map[string]any{
+			"title":  "Неверное правило",
+			"repeat": v.repeat,
+		}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, ret["error"], v.repeat)
+		assert.Equal(t, v.code, ret["code"], v.repeat)
+
+		query := url.Values{"date": {"20240126"}, "repeat": {v.repeat}}
+		body, err := requestJSON("api/nextdate/preview?"+query.Encode(), nil, http.MethodGet)
+		assert.NoError(t, err)
+		var m map[string]any
+		assert.NoError(t, json.Unmarshal(body, &m))
+		assert.Equal(t, v.code, m["code"], v.repeat)
+
+		query.Set("now", "20240126")
+		body, err = requestJSON("api/nextdate?"+query.Encode(), nil, http.MethodGet)
+		assert.NoError(t, err)
+		m = nil
+		assert.NoError(t, json.Unmarshal(body, &m))
+		assert.Equal(t, v.code, m["code"], v.repeat)
+	}
+
+	// Errors without a code don't have the field
+	ret, err := postJSON("api/task", map[string]any{"title": ""}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+	_, ok := ret["code"]
+	assert.False(t, ok)
+
+	// Rules are saved in their canonical form
+	id := addTask(t, task{title: "Каноническая форма", repeat: "m  07,19"})
+	body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var m map[string]any
+	assert.NoError(t, json.Unmarshal(body, &m))
+	assert.Equal(t, "m 7,19", m["repeat"])
+}
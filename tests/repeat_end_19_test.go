@@ -0,0 +1,75 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepeatEnd(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now()
+	id := addTask(t, task{
+		date:   now.Format(`20060102`),
+		title:  "Курс уколов",
+		repeat: "d 1 count 2",
+	})
+
+	ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	var row Task
+	assert.NoError(t, db.Get(&row, `SELECT * FROM scheduler WHERE id=?`, id))
+	assert.Equal(t, now.AddDate(0, 0, 1).Format(`20060102`), row.Date)
+	assert.Equal(t, 1, row.DoneCount)
+
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	notFoundTask(t, id)
+
+	// The finished series keeps the record of both completions
+	body, err := requestJSON("api/task/history?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var history historyResponse
+	assert.NoError(t, json.Unmarshal(body, &history))
+	if assert.Len(t, history.Completions, 2) {
+		assert.Equal(t, now.AddDate(0, 0, 1).Format(`20060102`), history.Completions[0]["date"])
+		assert.Equal(t, now.Format(`20060102`), history.Completions[1]["date"])
+	}
+
+	until := now.AddDate(0, 0, 10).Format(`20060102`)
+	id = addTask(t, task{
+		date:   now.Format(`20060102`),
+		title:  "Отпуск",
+		repeat: "d 7 until " + until,
+	})
+	body, err = requestJSON("api/calendar.ics", nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), "RRULE:FREQ=DAILY;INTERVAL=7;UNTIL="+until+"\r\n")
+
+	for i := 0; i < 2; i++ {
+		ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+	}
+	notFoundTask(t, id)
+
+	body, err = requestJSON("api/nextdate?now=20240126&date=20240113&repeat="+
+		strings.ReplaceAll("d 7 until 20240126", " ", "%20"), nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Empty(t, string(body))
+
+	ret, err = postJSON("api/task", map[string]any{
+		"title":  "Плохой конец",
+		"repeat": "d 7 count -1",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+}
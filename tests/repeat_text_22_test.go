@@ -0,0 +1,52 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// repeatTexts returns the repeat_text of the listed tasks by id
+func repeatTexts(t *testing.T, path, lang string) map[string]any {
+	body, err := requestWithHeaders(path, http.MethodGet, map[string]string{"Accept-Language": lang}, "")
+	assert.NoError(t, err)
+
+	var page tasksPage
+	assert.NoError(t, json.Unmarshal(body, &page))
+	texts := make(map[string]any)
+	for _, v := range page.Tasks {
+		texts[fmt.Sprint(v["id"])] = v["repeat_text"]
+	}
+	return texts
+}
+
+func TestRepeatText(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	today := time.Now().Format(`20060102`)
+	water := addTask(t, task{date: today, title: "Полив", repeat: "d 14"})
+	gym := addTask(t, task{date: today, title: "Спортзал", repeat: "w 1,3,5"})
+	once := addTask(t, task{date: today, title: "Разовая"})
+
+	texts := repeatTexts(t, "api/tasks?limit=500", "")
+	assert.Equal(t, "каждые 14 дней", texts[water])
+	assert.Equal(t, "по понедельникам, средам и пятницам", texts[gym])
+	assert.Contains(t, texts, once)
+	assert.Nil(t, texts[once])
+
+	texts = repeatTexts(t, "api/tasks?limit=500", "en-US,en;q=0.9,ru;q=0.8")
+	assert.Equal(t, "every 14 days", texts[water])
+	assert.Equal(t, "every Monday, Wednesday and Friday", texts[gym])
+	assert.Equal(t, "every 14 days", repeatTexts(t, "api/tasks/today", "en")[water])
+	assert.Equal(t, "каждые 14 дней", repeatTexts(t, "api/tasks/today", "ru;q=0.5,en;q=0")[water])
+
+	for _, id := range []string{water, gym, once} {
+		_, err := db.Exec(`DELETE FROM scheduler WHERE id = ?`, id)
+		assert.NoError(t, err)
+	}
+}
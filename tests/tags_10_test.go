@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func addTaggedTask(t *testing.T, title string, tags []string) string {
+	ret, err := postJSON("api/task", map[string]any{
+		"date":  time.Now().Format(`20060102`),
+		"title": title,
+		"tags":  tags,
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotNil(t, ret["id"])
+	return fmt.Sprint(ret["id"])
+}
+
+func taggedTitles(t *testing.T, query string) []string {
+	body, err := requestJSON("api/tasks?"+query, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var page tasksPage
+	assert.NoError(t, json.Unmarshal(body, &page))
+	var titles []string
+	for _, v := range page.Tasks {
+		titles = append(titles, fmt.Sprint(v["title"]))
+	}
+	sort.Strings(titles)
+	return titles
+}
+
+func TestTags(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	_, err := db.Exec("DELETE FROM scheduler")
+	assert.NoError(t, err)
+
+	addTaggedTask(t, "Отчёт", []string{"work", "Urgent"})
+	id := addTaggedTask(t, "Планёрка", []string{"work"})
+	addTaggedTask(t, "Купить хлеб", []string{"home"})
+
+	assert.Equal(t, []string{"Отчёт", "Планёрка"}, taggedTitles(t, "tag=work"))
+	assert.Equal(t, []string{"Отчёт"}, taggedTitles(t, "tag=work&tag=urgent"))
+	assert.Empty(t, taggedTitles(t, "tag=home&tag=work"))
+
+	body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var task map[string]any
+	assert.NoError(t, json.Unmarshal(body, &task))
+	assert.Equal(t, []any{"work"}, task["tags"])
+
+	// Updating without tags keeps them, an explicit list replaces them
+	ret, err := postJSON("api/task", map[string]any{
+		"id":    id,
+		"date":  time.Now().Format(`20060102`),
+		"title": "Планёрка",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.Equal(t, []string{"Отчёт", "Планёрка"}, taggedTitles(t, "tag=work"))
+
+	ret, err = postJSON("api/task", map[string]any{
+		"id":    id,
+		"date":  time.Now().Format(`20060102`),
+		"title": "Планёрка",
+		"tags":  []string{"urgent"},
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.Equal(t, []string{"Отчёт"}, taggedTitles(t, "tag=work"))
+
+	body, err = requestJSON("api/tags", nil, http.MethodGet)
+	assert.NoError(t, err)
+	var m struct {
+		Tags []struct {
+			Name  string `json:"name"`
+			Count int    `json:"count"`
+		} `json:"tags"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &m))
+	counts := map[string]int{}
+	for _, v := range m.Tags {
+		counts[v.Name] = v.Count
+	}
+	assert.Equal(t, map[string]int{"home": 1, "urgent": 2, "work": 1}, counts)
+}
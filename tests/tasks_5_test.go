@@ -32,10 +32,22 @@ func getTasks(t *testing.T, search string) []map[string]string {
 	body, err := requestJSON(url, nil, http.MethodGet)
 	assert.NoError(t, err)
 
-	var m map[string][]map[string]string
+	// List responses also carry non-string fields such as "overdue"
+	var m map[string][]map[string]any
 	err = json.Unmarshal(body, &m)
 	assert.NoError(t, err)
-	return m["tasks"]
+	if m["tasks"] == nil {
+		return nil
+	}
+	tasks := make([]map[string]string, 0, len(m["tasks"]))
+	for _, v := range m["tasks"] {
+		task := make(map[string]string, len(v))
+		for key, value := range v {
+			task[key] = fmt.Sprint(value)
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks
 }
 
 func TestTasks(t *testing.T) {
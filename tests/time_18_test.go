@@ -0,0 +1,71 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskTime(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	_, err := db.Exec("DELETE FROM scheduler")
+	assert.NoError(t, err)
+
+	date := time.Now().AddDate(0, 0, 1).Format(`20060102`)
+	for _, v := range []map[string]any{
+		{"date": date, "title": "Созвон", "time": "14:30", "duration": 45},
+		{"date": date, "title": "Завтрак", "time": "8:00"},
+		{"date": date, "title": "Весь день"},
+	} {
+		ret, err := postJSON("api/task", v, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotNil(t, ret["id"])
+	}
+	ret, err := postJSON("api/task", map[string]any{"title": "Без времени", "duration": 30}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	body, err := requestJSON("api/tasks", nil, http.MethodGet)
+	assert.NoError(t, err)
+	var page tasksPage
+	assert.NoError(t, json.Unmarshal(body, &page))
+	var list []string
+	for _, v := range page.Tasks {
+		list = append(list, fmt.Sprint(v["title"], " ", v["time"], " ", v["duration"]))
+	}
+	assert.Equal(t, []string{"Весь день <nil> <nil>", "Завтрак 08:00 <nil>", "Созвон 14:30 45"}, list)
+
+	// Timed tasks are exported with a UTC start and end, all-day ones with dates
+	start, err := time.ParseInLocation("20060102 15:04", date+" 14:30", time.Local)
+	assert.NoError(t, err)
+	body, err = requestJSON("api/calendar.ics", nil, http.MethodGet)
+	assert.NoError(t, err)
+	ics := string(body)
+	assert.Contains(t, ics, "DTSTART:"+start.UTC().Format("20060102T150405Z")+"\r\n")
+	assert.Contains(t, ics, "DTEND:"+start.Add(45*time.Minute).UTC().Format("20060102T150405Z")+"\r\n")
+	assert.Contains(t, ics, "DTSTART;VALUE=DATE:"+date+"\r\n")
+
+	_, err = db.Exec("DELETE FROM scheduler")
+	assert.NoError(t, err)
+
+	// Importing the feed back keeps the time and duration
+	body, err = requestWithHeaders("api/import/ics", http.MethodPost, nil, ics)
+	assert.NoError(t, err)
+	assert.True(t, strings.Contains(string(body), `"imported":3`), string(body))
+
+	var task Task
+	assert.NoError(t, db.Get(&task, `SELECT * FROM scheduler WHERE title = ?`, "Созвон"))
+	assert.Equal(t, date, task.Date)
+	assert.Equal(t, "14:30", task.Time)
+	assert.Equal(t, 45, task.Duration)
+	assert.NoError(t, db.Get(&task, `SELECT * FROM scheduler WHERE title = ?`, "Весь день"))
+	assert.Equal(t, "", task.Time)
+	assert.Equal(t, 0, task.Duration)
+}
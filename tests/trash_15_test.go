@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func trashIDs(t *testing.T) []string {
+	body, err := requestJSON("api/trash", nil, http.MethodGet)
+	assert.NoError(t, err)
+	var page tasksPage
+	assert.NoError(t, json.Unmarshal(body, &page))
+	ids := []string{}
+	for _, v := range page.Tasks {
+		assert.NotEmpty(t, v["deleted_at"])
+		ids = append(ids, fmt.Sprint(v["id"]))
+	}
+	return ids
+}
+
+func TestTrash(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	id := addTask(t, task{
+		title:   "Не потерять",
+		comment: "Удалено по ошибке",
+	})
+
+	ret, err := postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	notFoundTask(t, id)
+	assert.Contains(t, trashIDs(t), id)
+
+	// The row is kept until the trash is purged
+	var deletedAt string
+	assert.NoError(t, db.Get(&deletedAt, `SELECT deleted_at FROM scheduler WHERE id = ?`, id))
+	assert.NotEmpty(t, deletedAt)
+
+	ret, err = postJSON("api/task/restore?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.NotContains(t, trashIDs(t), id)
+
+	body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var m map[string]any
+	assert.NoError(t, json.Unmarshal(body, &m))
+	assert.Equal(t, "Не потерять", m["title"])
+	assert.Nil(t, m["deleted_at"])
+
+	ret, err = postJSON("api/task/restore?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+	ret, err = postJSON("api/task/restore", nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+}